@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+
+	"github.com/mastercactapus/cncgui/spjs"
+)
+
+const (
+	// consoleMaxLines is the number of log lines kept in the console.
+	consoleMaxLines = 500
+
+	// consoleMaxHistory is the number of sent commands saved between runs.
+	consoleMaxHistory = 50
+
+	consoleHistoryKey = "consoleHistory"
+)
+
+// NewConsole returns an MDI console that sends commands to the controller and shows the raw serial log.
+func NewConsole(ctx context.Context, ctrl *spjs.Controller, prefs fyne.Preferences, w fyne.Window) fyne.CanvasObject {
+	var history []string
+	if h := prefs.String(consoleHistoryKey); h != "" {
+		history = strings.Split(h, "\n")
+	}
+
+	input := widget.NewSelectEntry(history)
+	input.SetPlaceHolder("G-code or $ command")
+
+	showStatus := widget.NewCheck("Status reports", nil)
+
+	logText := widget.NewLabel("")
+	logText.TextStyle.Monospace = true
+	logText.Wrapping = fyne.TextWrapBreak
+	scroll := widget.NewVScrollContainer(logText)
+
+	var mx sync.Mutex
+	var lines []string
+	go func() {
+		for entry := range ctrl.Log() {
+			data := strings.TrimRight(entry.Data, "\r\n")
+			if !entry.Sent && strings.HasPrefix(data, "<") && !showStatus.Checked {
+				continue
+			}
+			prefix := "< "
+			if entry.Sent {
+				prefix = "> "
+			}
+			mx.Lock()
+			lines = append(lines, prefix+entry.Time.Format("15:04:05")+" "+strings.ReplaceAll(data, "\n", "\n  "))
+			if len(lines) > consoleMaxLines {
+				lines = lines[len(lines)-consoleMaxLines:]
+			}
+			logText.SetText(strings.Join(lines, "\n"))
+			mx.Unlock()
+			scroll.ScrollToBottom()
+		}
+	}()
+
+	send := func() {
+		cmd := strings.TrimSpace(input.Text)
+		if cmd == "" {
+			return
+		}
+		err := ctrl.SendCommand(ctx, cmd+"\n", false)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		input.SetText("")
+
+		newHistory := []string{cmd}
+		for _, h := range history {
+			if h == cmd {
+				continue
+			}
+			newHistory = append(newHistory, h)
+		}
+		if len(newHistory) > consoleMaxHistory {
+			newHistory = newHistory[:consoleMaxHistory]
+		}
+		history = newHistory
+		input.SetOptions(history)
+		prefs.SetString(consoleHistoryKey, strings.Join(history, "\n"))
+	}
+
+	command := func(fn func(context.Context) error) func() {
+		return func() {
+			err := fn(ctx)
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+		}
+	}
+
+	realtime := fyne.NewContainerWithLayout(layout.NewHBoxLayout(),
+		widget.NewButton("Status", command(ctrl.CommandStatusQuery)),
+		widget.NewButton("Hold (!)", command(ctrl.CommandFeedHold)),
+		widget.NewButton("Resume (~)", command(ctrl.CommandCycleStart)),
+		widget.NewButton("Reset (^X)", command(ctrl.CommandReset)),
+		layout.NewSpacer(),
+		showStatus,
+		widget.NewButton("Clear", func() {
+			mx.Lock()
+			lines = nil
+			logText.SetText("")
+			mx.Unlock()
+		}),
+	)
+
+	sendBtn := widget.NewButton("Send", send)
+	entry := fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, sendBtn), sendBtn, input)
+
+	return fyne.NewContainerWithLayout(layout.NewBorderLayout(realtime, entry, nil, nil),
+		realtime, entry, scroll,
+	)
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"image"
 	"log"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne"
 	"fyne.io/fyne/app"
+	"fyne.io/fyne/canvas"
 	"fyne.io/fyne/dialog"
 	"fyne.io/fyne/layout"
 	"fyne.io/fyne/storage"
@@ -19,6 +23,9 @@ import (
 	"github.com/mastercactapus/cncgui/spjs"
 )
 
+// statusStaleAge is how old the controller status can get before it is marked in the UI.
+const statusStaleAge = 2 * time.Second
+
 type paddedTheme struct {
 	fyne.Theme
 }
@@ -28,30 +35,93 @@ func (p paddedTheme) Padding() int { return 8 }
 func main() {
 	spjsURL := flag.String("spjs", "ws://localhost:8989/ws", "Set the SPJS connection URL.")
 	full := flag.Bool("fullscreen", false, "Run in fullscreen.")
+	firmware := flag.String("firmware", "auto", "Set the controller firmware (auto, grbl, grblhal, smoothie, marlin, tinyg or g2core).")
+	pendantProto := flag.String("pendant-protocol", "", "Load the pendant protocol from a JSON file.")
+	inputBindings := flag.String("input", "", "Load input device (gamepad, keypad, jog remote) bindings from a JSON file. Linux only.")
+	baud := flag.Int("baud", 115200, "Set the baud rate used to detect the controller firmware with -firmware=auto.")
 	flag.Parse()
 	log.SetFlags(log.Lshortfile)
 
 	log.Println("START")
+	a := app.NewWithID("com.github.mastercactapus.cncgui")
+	ctx := context.Background()
+	settings := LoadSettings(a.Preferences())
+
+	var drv spjs.Driver
+	switch *firmware {
+	case "auto":
+		drv = spjs.NewDetector(*baud)
+	case "grbl":
+		drv = spjs.NewGRBL()
+	case "grblhal":
+		drv = spjs.NewGRBLHAL()
+	case "smoothie":
+		drv = spjs.NewSmoothie()
+	case "marlin":
+		drv = spjs.NewMarlin()
+	case "tinyg":
+		drv = spjs.NewTinyG()
+	case "g2core":
+		drv = spjs.NewG2Core()
+	default:
+		log.Fatalf("unknown firmware '%s'", *firmware)
+	}
+
 	cli := spjs.NewClient(*spjsURL)
-	grbl := cli.NewPort(spjs.NewVIDPIDMatcher("2a03", "0043"), spjs.NewGRBL()).NewController()
+	grbl := cli.NewPort(settings.GRBLMatcher(), drv).NewController()
 	pendant := spjs.NewArduinoPendant(grbl)
-	cli.NewPort(spjs.NewVIDPIDMatcher("1a86", "7523"), pendant)
+	if *pendantProto != "" {
+		fd, err := os.Open(*pendantProto)
+		if err != nil {
+			log.Fatalln("ERROR: open pendant protocol:", err)
+		}
+		pp, err := spjs.LoadPendantProtocol(fd)
+		fd.Close()
+		if err != nil {
+			log.Fatalln("ERROR: load pendant protocol:", err)
+		}
+		pendant.SetProtocol(pp)
+	}
+	if *inputBindings != "" {
+		fd, err := os.Open(*inputBindings)
+		if err != nil {
+			log.Fatalln("ERROR: open input bindings:", err)
+		}
+		b, err := spjs.LoadEvdevBindings(fd)
+		fd.Close()
+		if err != nil {
+			log.Fatalln("ERROR: load input bindings:", err)
+		}
+		go func() {
+			err := spjs.NewEvdevInput(grbl, b).Run(ctx)
+			if err != nil {
+				log.Println("ERROR: input devices:", err)
+			}
+		}()
+	}
+	pendantPort := cli.NewPort(settings.PendantMatcher(), pendant)
 
-	a := app.New()
-	ctx := context.Background()
+	// units are only used for display and entry, all values sent to the controller are in mm
+	units := spjs.Units(a.Preferences().Int("units"))
+	pendant.SetUnits(units)
 
 	var st spjs.ControllerStatus
 	var jobSt spjs.JobStatus
 	var refreshFns []func()
 
 	go func() {
+		// refresh periodically even without updates, so a stale status is shown
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
 		for {
 			select {
 			case st = <-grbl.Status():
 			case jobSt = <-grbl.JobStatus():
+			case <-t.C:
 			}
 			if st == nil {
-				return
+				// nothing to show until the first status report
+				continue
 			}
 			for _, fn := range refreshFns {
 				fn()
@@ -60,24 +130,61 @@ func main() {
 	}()
 
 	w := a.NewWindow("CNC GUI")
-	w.Resize(fyne.NewSize(800, 480))
+	w.Resize(fyne.NewSize(settings.WindowWidth, settings.WindowHeight))
 	w.SetFixedSize(true)
 	if *full {
 		w.SetFullScreen(true)
 	}
 	readyCh := make(chan struct{})
+	grblEvents := grbl.Events()
 	go func() {
 		<-readyCh
 
-		var grblWait *dialog.ProgressInfiniteDialog
-		t := time.NewTicker(100 * time.Millisecond)
-		for range t.C {
-			if !grbl.Connected() && grblWait == nil {
-				grblWait = dialog.NewProgressInfinite("Connecting to GRBL", "The CNC controller board (GRBL) is not connected...", w)
-			} else if grbl.Connected() && grblWait != nil {
-				grblWait.Hide()
-				grblWait = nil
+		const (
+			waitNone = iota
+			waitSPJS
+			waitGRBL
+		)
+		var wait *dialog.ProgressInfiniteDialog
+		var waitKind int
+		update := func(conn spjs.ConnStatus) {
+			kind := waitNone
+			switch {
+			case conn.State != spjs.ConnConnected && conn.Failures > 0:
+				kind = waitSPJS
+			case !grbl.Connected():
+				kind = waitGRBL
+			}
+			if kind == waitKind {
+				return
+			}
+			if wait != nil {
+				wait.Hide()
+				wait = nil
+			}
+			waitKind = kind
+			switch kind {
+			case waitSPJS:
+				msg := "The serial port server (SPJS) at " + *spjsURL + " can not be reached, retrying..."
+				if conn.LastErr != nil {
+					msg += "\n\n" + conn.LastErr.Error()
+				}
+				wait = dialog.NewProgressInfinite("SPJS Server Unreachable", msg, w)
+			case waitGRBL:
+				wait = dialog.NewProgressInfinite("Connecting to GRBL", "The CNC controller board (GRBL) is not connected...", w)
+			}
+		}
+		conn := cli.LastConnStatus()
+		update(conn)
+		for {
+			select {
+			case conn = <-cli.ConnStatus():
+			case _, ok := <-grblEvents:
+				if !ok {
+					return
+				}
 			}
+			update(conn)
 		}
 	}()
 
@@ -96,7 +203,7 @@ func main() {
 		}, w)
 	})
 	load := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
-		lister, err := storage.ListerForURI(storage.NewURI("file:///home/nathan/cnc/cncgui"))
+		lister, err := storage.ListerForURI(storage.NewFileURI(settings.FileRoot))
 		if err != nil {
 			log.Println("ERROR:", err)
 			return
@@ -119,7 +226,7 @@ func main() {
 		}, w)
 
 		open.SetLocation(lister)
-		open.SetFilter(storage.NewExtensionFileFilter([]string{".nc"}))
+		open.SetFilter(storage.NewExtensionFileFilter(settings.FileExtensions))
 		open.Show()
 	})
 
@@ -160,7 +267,19 @@ func main() {
 	pendStatus := widget.NewLabel("Pendant: Not Connected")
 
 	refreshFns = append(refreshFns, func() {
-		status.SetText("GRBL Status: " + st.StatusText())
+		feedFmt := "GRBL Status: %s (F%.f %s/min)"
+		if units == spjs.Inches {
+			feedFmt = "GRBL Status: %s (F%.2f %s/min)"
+		}
+		msg := fmt.Sprintf(feedFmt, st.StatusText(), units.FromMM(st.FeedRate()), units)
+		if age, err := grbl.StatusAge(); err == nil && age > statusStaleAge {
+			if grbl.StatusDeferred() {
+				msg += fmt.Sprintf(" [not polled while commands are queued, %s old]", age.Truncate(time.Second))
+			} else {
+				msg += fmt.Sprintf(" [no update for %s]", age.Truncate(time.Second))
+			}
+		}
+		status.SetText(msg)
 		pend := "Connected"
 		if !pendant.Connected() {
 			pend = "Not Connected"
@@ -168,9 +287,34 @@ func main() {
 		pendStatus.SetText("Pendant: " + pend)
 	})
 
+	// the pendant may be the operator's e-stop, losing it must not go unnoticed
+	go func() {
+		<-readyCh
+		for lost := range pendant.Lost() {
+			msg := "The pendant was lost (" + lost.Reason + ") at " + lost.Time.Format("15:04:05") + "."
+			switch {
+			case lost.Err != nil:
+				msg += "\n\nFailed to stop MPG motion: " + lost.Err.Error()
+			case lost.Stopped:
+				msg += "\n\nMPG motion was stopped."
+			}
+			dialog.ShowInformation("Pendant Lost", msg, w)
+		}
+	}()
+
+	// setUnits is assigned once the jog step selector exists
+	var setUnits func(spjs.Units)
+	unitsToggle := widget.NewButton(units.String(), func() {
+		if units == spjs.Inches {
+			setUnits(spjs.Millimeters)
+		} else {
+			setUnits(spjs.Inches)
+		}
+	})
+
 	actions := fyne.NewContainerWithLayout(layout.NewHBoxLayout(),
 		fyne.NewContainerWithLayout(NewSquareHBoxLayout(64),
-			home, load, runJob, cycleStart, feedHold, resetCancel,
+			home, load, runJob, cycleStart, feedHold, resetCancel, unitsToggle,
 		),
 		fyne.NewContainerWithLayout(layout.NewVBoxLayout(), status, pendStatus),
 	)
@@ -182,39 +326,90 @@ func main() {
 		return l
 	}
 
-	wPosX := NewPos()
-	wPosY := NewPos()
-	wPosZ := NewPos()
-	mPosX := NewPos()
-	mPosY := NewPos()
-	mPosZ := NewPos()
-	refreshFns = append(refreshFns, func() {
-		set := func(l *widget.Label, v float64) { l.SetText(fmt.Sprintf("%10.3f", v)) }
-		wpos := st.WorkPosition()
-		mpos := st.MachinePosition()
-		set(wPosX, wpos.X)
-		set(wPosY, wpos.Y)
-		set(wPosZ, wpos.Z)
-		set(mPosX, mpos.X)
-		set(mPosY, mpos.Y)
-		set(mPosZ, mpos.Z)
-	})
-
 	wpos := widget.NewLabel("WPos")
 	wpos.Alignment = fyne.TextAlignTrailing
 	mpos := widget.NewLabel("MPos")
 	mpos.Alignment = fyne.TextAlignTrailing
 
 	posRead := fyne.NewContainerWithLayout(layout.NewGridLayout(4),
-		wpos, wPosX, wPosY, wPosZ,
+		widget.NewLabel(""), wpos, mpos, widget.NewLabel(""),
+	)
+	// toDisplay and fromDisplay convert linear axis values between mm and the selected display units
+	toDisplay := func(axis rune, mm float64) float64 {
+		if spjs.IsRotaryAxis(axis) {
+			return mm
+		}
+		return units.FromMM(mm)
+	}
+	fromDisplay := func(axis rune, val float64) float64 {
+		if spjs.IsRotaryAxis(axis) {
+			return val
+		}
+		return units.ToMM(val)
+	}
 
-		mpos, mPosX, mPosY, mPosZ,
+	for _, axis := range spjs.Axes {
+		axis := axis
+		name := widget.NewButton(string(axis), func() {
+			entry := widget.NewEntry()
+			entry.SetPlaceHolder("0.000")
+			unitName := units.String()
+			if spjs.IsRotaryAxis(axis) {
+				unitName = "deg"
+			}
+			dialog.ShowCustomConfirm(fmt.Sprintf("Set %c work position (%s)", axis, unitName), "Set", "Cancel", entry, func(ok bool) {
+				if !ok {
+					return
+				}
+				val, err := strconv.ParseFloat(strings.TrimSpace(entry.Text), 64)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("invalid position '%s'", entry.Text), w)
+					return
+				}
+				err = grbl.SetWPos(ctx, axis, fromDisplay(axis, val))
+				if err != nil {
+					dialog.ShowError(err, w)
+				}
+			}, w)
+		})
+		wPos := NewPos()
+		mPos := NewPos()
+		zero := widget.NewButton(string(axis)+"=0", func() { grbl.SetWPos(ctx, axis, 0) })
+		row := []fyne.CanvasObject{name, wPos, mPos, zero}
+		posRead.Objects = append(posRead.Objects, row...)
+
+		// rotary axes are only shown if the controller reports them
+		hidden := strings.IndexRune(spjs.Axes, axis) >= 3
+		if hidden {
+			for _, obj := range row {
+				obj.Hide()
+			}
+		}
 
-		widget.NewLabel(""),
-		widget.NewButton("X=0", func() { grbl.SetWPos(ctx, 'X', 0) }),
-		widget.NewButton("Y=0", func() { grbl.SetWPos(ctx, 'Y', 0) }),
-		widget.NewButton("Z=0", func() { grbl.SetWPos(ctx, 'Z', 0) }),
-	)
+		refreshFns = append(refreshFns, func() {
+			format := "%10.3f"
+			if units == spjs.Inches && !spjs.IsRotaryAxis(axis) {
+				format = "%10.4f"
+			}
+			set := func(l *widget.Label, v float64) { l.SetText(fmt.Sprintf(format, toDisplay(axis, v))) }
+			set(wPos, st.WorkPosition().Axis(axis))
+			set(mPos, st.MachinePosition().Axis(axis))
+
+			shouldHide := strings.IndexRune(spjs.Axes, axis) >= st.AxisCount()
+			if shouldHide == hidden {
+				return
+			}
+			hidden = shouldHide
+			for _, obj := range row {
+				if hidden {
+					obj.Hide()
+				} else {
+					obj.Show()
+				}
+			}
+			posRead.Refresh()
+		})
+	}
 
 	centerLabel := func(text string) fyne.CanvasObject {
 		label := widget.NewLabel(text)
@@ -225,10 +420,25 @@ func main() {
 	zUp := widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil)
 	zDn := widget.NewButtonWithIcon("", theme.MoveDownIcon(), nil)
 
-	mult := "10"
-	sel := widget.NewRadioGroup([]string{
-		"100", "10", "1", "0.1", "0.01", "0.001",
-	}, nil)
+	// the Z column can be switched to jog any available rotary axis
+	zAxis := 'Z'
+	zSel := widget.NewButton("Z", nil)
+	zSel.OnTapped = func() {
+		idx := strings.IndexRune(spjs.Axes, zAxis) + 1
+		if st == nil || idx >= st.AxisCount() {
+			idx = 2
+		}
+		zAxis = rune(spjs.Axes[idx])
+		zSel.SetText(string(zAxis))
+	}
+
+	// jog steps for each unit, the largest step is not allowed for the Z column
+	steps := map[spjs.Units][]string{
+		spjs.Millimeters: {"100", "10", "1", "0.1", "0.01", "0.001"},
+		spjs.Inches:      {"10", "1", "0.1", "0.01", "0.001", "0.0001"},
+	}
+	mult := steps[units][1]
+	sel := widget.NewRadioGroup(steps[units], nil)
 	sel.OnChanged = func(val string) {
 		if val == "" {
 			val = mult
@@ -236,7 +446,7 @@ func main() {
 			return
 		}
 		mult = val
-		if val == "100" {
+		if val == steps[units][0] {
 			zUp.Disable()
 			zDn.Disable()
 		} else {
@@ -244,30 +454,51 @@ func main() {
 			zDn.Enable()
 		}
 	}
-	sel.SetSelected("10")
+	sel.SetSelected(mult)
+
+	setUnits = func(u spjs.Units) {
+		units = u
+		a.Preferences().SetInt("units", int(u))
+		pendant.SetUnits(u)
+		unitsToggle.SetText(u.String())
+
+		mult = steps[u][1]
+		sel.Options = steps[u]
+		sel.SetSelected(mult)
+		sel.Refresh()
+
+		if st == nil {
+			return
+		}
+		for _, fn := range refreshFns {
+			fn()
+		}
+	}
 
-	makeMove := func(axis rune, invert bool) func() {
+	// makeMove returns a handler that jogs each axis in `axes` by the selected step in the direction of its matching sign.
+	makeMove := func(axes string, signs ...float64) func() {
 		return func() {
 			val, err := strconv.ParseFloat(mult, 64)
 			if err != nil {
 				panic(err)
 			}
-			if invert {
-				val = -val
+			moves := make([]spjs.AxisMove, 0, len(signs))
+			for i, axis := range axes {
+				moves = append(moves, spjs.AxisMove{Axis: axis, MM: fromDisplay(axis, val*signs[i])})
 			}
-			err = grbl.CommandJog(ctx, axis, val, false)
+			err = grbl.CommandJogAxes(ctx, moves, false)
 			if err != nil {
 				dialog.ShowError(err, w)
 			}
 		}
 	}
-	zUp.OnTapped = makeMove('Z', false)
-	zDn.OnTapped = makeMove('Z', true)
+	zUp.OnTapped = func() { makeMove(string(zAxis), 1)() }
+	zDn.OnTapped = func() { makeMove(string(zAxis), -1)() }
 
 	touchPendant := fyne.NewContainerWithLayout(NewSquareGridLayout(5, 64),
-		zUp, layout.NewSpacer(), layout.NewSpacer(), widget.NewButtonWithIcon("", theme.MoveUpIcon(), makeMove('Y', false)), layout.NewSpacer(),
-		centerLabel("Z"), layout.NewSpacer(), widget.NewButtonWithIcon("<", nil, makeMove('X', true)), centerLabel("XY"), widget.NewButtonWithIcon(">", nil, makeMove('X', false)),
-		zDn, layout.NewSpacer(), layout.NewSpacer(), widget.NewButtonWithIcon("", theme.MoveDownIcon(), makeMove('Y', true)), layout.NewSpacer(),
+		zUp, layout.NewSpacer(), widget.NewButton("-X+Y", makeMove("XY", -1, 1)), widget.NewButtonWithIcon("", theme.MoveUpIcon(), makeMove("Y", 1)), widget.NewButton("+X+Y", makeMove("XY", 1, 1)),
+		zSel, layout.NewSpacer(), widget.NewButtonWithIcon("<", nil, makeMove("X", -1)), centerLabel("XY"), widget.NewButtonWithIcon(">", nil, makeMove("X", 1)),
+		zDn, layout.NewSpacer(), widget.NewButton("-X-Y", makeMove("XY", -1, -1)), widget.NewButtonWithIcon("", theme.MoveDownIcon(), makeMove("Y", -1)), widget.NewButton("+X-Y", makeMove("XY", 1, -1)),
 	)
 
 	pos := fyne.NewContainerWithLayout(
@@ -275,6 +506,77 @@ func main() {
 		sel, touchPendant, layout.NewSpacer(), posRead,
 	)
 
+	rpm := widget.NewEntry()
+	rpm.SetText("10000")
+	spindleOn := func(ccw bool) func() {
+		return func() {
+			val, err := strconv.ParseFloat(strings.TrimSpace(rpm.Text), 64)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid spindle speed '%s'", rpm.Text), w)
+				return
+			}
+			err = grbl.CommandSpindleOn(ctx, val, ccw)
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+		}
+	}
+	spindleCW := widget.NewButton("CW", spindleOn(false))
+	spindleCCW := widget.NewButton("CCW", spindleOn(true))
+	spindleOff := widget.NewButton("Off", func() {
+		err := grbl.CommandSpindleOff(ctx)
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	})
+	flood := widget.NewButton("Flood", func() {
+		err := grbl.CommandToggleFlood(ctx)
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	})
+	mist := widget.NewButton("Mist", func() {
+		err := grbl.CommandToggleMist(ctx)
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	})
+	spindleStatus := widget.NewLabel("Spindle: Off")
+	refreshFns = append(refreshFns, func() {
+		acc := st.Accessories()
+		highlight := func(b *widget.Button, active bool) {
+			imp := widget.MediumImportance
+			if active {
+				imp = widget.HighImportance
+			}
+			if b.Importance == imp {
+				return
+			}
+			b.Importance = imp
+			b.Refresh()
+		}
+		highlight(spindleCW, acc.SpindleEnabled && !acc.SpindleCCW)
+		highlight(spindleCCW, acc.SpindleEnabled && acc.SpindleCCW)
+		highlight(flood, acc.Flood)
+		highlight(mist, acc.Mist)
+
+		switch {
+		case !acc.SpindleEnabled:
+			spindleStatus.SetText("Spindle: Off")
+		case acc.SpindleCCW:
+			spindleStatus.SetText(fmt.Sprintf("Spindle: CCW %.f RPM", acc.SpindleSpeed))
+		default:
+			spindleStatus.SetText(fmt.Sprintf("Spindle: CW %.f RPM", acc.SpindleSpeed))
+		}
+	})
+
+	spindle := fyne.NewContainerWithLayout(layout.NewHBoxLayout(),
+		widget.NewLabel("RPM"), fyne.NewContainerWithLayout(layout.NewGridWrapLayout(fyne.NewSize(96, rpm.MinSize().Height)), rpm),
+		spindleCW, spindleCCW, spindleOff, spindleStatus,
+		layout.NewSpacer(),
+		flood, mist,
+	)
+
 	jobStatus := widget.NewLabel("No active job.")
 	jobProgress := widget.NewProgressBar()
 	jobProgress.TextFormatter = func() string {
@@ -301,6 +603,10 @@ func main() {
 		msg := fmt.Sprintf("Job: %s", jobSt.Name)
 		if jobSt.Err != nil {
 			msg += " (error: " + jobSt.Err.Error() + ")"
+		} else if jobSt.Held {
+			msg += " (held: " + jobSt.HoldReason + ")"
+		} else if jobSt.Done {
+			msg += " (done)"
 		} else if !jobSt.Active {
 			msg += " (paused)"
 		}
@@ -311,18 +617,101 @@ func main() {
 		}
 	})
 
+	streamStatus := widget.NewLabel("")
+	refreshFns = append(refreshFns, func() {
+		q := grbl.QueueStats()
+		msg := fmt.Sprintf("Queued: %d  Written: %d  Done: %d", q.Queued, q.Written, q.Completed)
+		if q.PlannerFree >= 0 {
+			msg += fmt.Sprintf("  Planner: %d  RX: %d", q.PlannerFree, q.RXFree)
+		}
+		if unknown, invalid := cli.BadMessageCounts(); unknown+invalid > 0 {
+			msg += fmt.Sprintf("  Bad SPJS messages: %d unknown, %d invalid", unknown, invalid)
+		}
+		streamStatus.SetText(msg)
+	})
+
+	heldInfo := widget.NewLabel("")
+	var heldDialog dialog.Dialog
+	refreshFns = append(refreshFns, func() {
+		if !jobSt.Held {
+			if heldDialog != nil {
+				// Hide calls the callback, clear it first so it is ignored
+				d := heldDialog
+				heldDialog = nil
+				d.Hide()
+			}
+			return
+		}
+
+		heldInfo.SetText(fmt.Sprintf("The job was held because of: %s.\n\nSent: %d, Completed: %d, Unconfirmed: %d, To resend: %d, Queued in SPJS: %d\n\nCheck the machine before continuing.",
+			jobSt.HoldReason, jobSt.Sent, jobSt.Completed, jobSt.Unconfirmed, jobSt.Requeued, jobSt.Queued,
+		))
+		if heldDialog != nil {
+			return
+		}
+		var d dialog.Dialog
+		d = dialog.NewCustomConfirm("Job Held", "Continue", "Cancel Job", heldInfo, func(resume bool) {
+			if heldDialog != d {
+				return
+			}
+			heldDialog = nil
+			var err error
+			if resume {
+				err = grbl.ConfirmJob(ctx)
+			} else {
+				err = grbl.CommandReset(ctx)
+			}
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+		}, w)
+		heldDialog = d
+		d.Show()
+	})
+
 	grp := widget.NewGroup("Job",
-		fyne.NewContainerWithLayout(layout.NewHBoxLayout(), jobStatus),
+		fyne.NewContainerWithLayout(layout.NewHBoxLayout(), jobStatus, layout.NewSpacer(), streamStatus),
 		jobProgress,
 	)
-	w.SetContent(fyne.NewContainerWithLayout(
-		layout.NewVBoxLayout(), actions, pos,
-		layout.NewSpacer(),
-		grp,
-	))
+	// the raster is only regenerated when the window is drawn, so a stalled UI will cause the watchdog
+	// to hold a running job
+	heartbeat := canvas.NewRaster(func(_, _ int) image.Image {
+		grbl.Kick()
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	})
+	go func() {
+		<-readyCh
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		for range t.C {
+			canvas.Refresh(heartbeat)
+		}
+	}()
+
+	w.SetContent(fyne.NewContainerWithLayout(layout.NewMaxLayout(), heartbeat, widget.NewTabContainer(
+		widget.NewTabItem("Machine", fyne.NewContainerWithLayout(
+			layout.NewVBoxLayout(), actions, pos, spindle,
+			layout.NewSpacer(),
+			grp,
+		)),
+		widget.NewTabItem("Console", NewConsole(ctx, grbl, a.Preferences(), w)),
+		widget.NewTabItem("Settings", NewSettingsEditor(a.Preferences(), cli, w, func(s Settings) {
+			settings = s
+			grbl.SetMatcher(s.GRBLMatcher())
+			pendantPort.SetMatcher(s.PendantMatcher())
+			w.Resize(fyne.NewSize(s.WindowWidth, s.WindowHeight))
+		})),
+	)))
 
 	fmt.Println("Launch")
 	w.Show()
 	close(readyCh)
 	a.Run()
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := cli.Close(shutdownCtx)
+	if err != nil {
+		log.Println("ERROR: close SPJS client:", err)
+	}
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+
+	"github.com/mastercactapus/cncgui/spjs"
+)
+
+// Settings are the per-machine options saved between runs.
+type Settings struct {
+	GRBLVID, GRBLPID       string
+	PendantVID, PendantPID string
+
+	// GRBLSerial and PendantSerial are optional, and can be used to tell apart identical devices.
+	GRBLSerial, PendantSerial string
+
+	// GRBLPort and PendantPort are optional port name expressions, for identical devices without a serial number.
+	GRBLPort, PendantPort string
+
+	// FileRoot is the directory the job file browser starts in.
+	FileRoot string
+
+	// FileExtensions limits the job file browser to matching files.
+	FileExtensions []string
+
+	WindowWidth, WindowHeight int
+}
+
+// LoadSettings will read settings from the app preferences, using defaults for any missing values.
+func LoadSettings(prefs fyne.Preferences) Settings {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "/"
+	}
+
+	return Settings{
+		GRBLVID:    prefs.StringWithFallback("grblVID", "2a03"),
+		GRBLPID:    prefs.StringWithFallback("grblPID", "0043"),
+		PendantVID: prefs.StringWithFallback("pendantVID", "1a86"),
+		PendantPID: prefs.StringWithFallback("pendantPID", "7523"),
+
+		GRBLSerial:    prefs.String("grblSerial"),
+		PendantSerial: prefs.String("pendantSerial"),
+		GRBLPort:      prefs.String("grblPort"),
+		PendantPort:   prefs.String("pendantPort"),
+
+		FileRoot:       prefs.StringWithFallback("fileRoot", home),
+		FileExtensions: strings.Split(prefs.StringWithFallback("fileExtensions", ".nc"), ","),
+
+		WindowWidth:  prefs.IntWithFallback("windowWidth", 800),
+		WindowHeight: prefs.IntWithFallback("windowHeight", 480),
+	}
+}
+
+// Save will store all settings in the app preferences.
+func (s Settings) Save(prefs fyne.Preferences) {
+	prefs.SetString("grblVID", s.GRBLVID)
+	prefs.SetString("grblPID", s.GRBLPID)
+	prefs.SetString("pendantVID", s.PendantVID)
+	prefs.SetString("pendantPID", s.PendantPID)
+	prefs.SetString("grblSerial", s.GRBLSerial)
+	prefs.SetString("pendantSerial", s.PendantSerial)
+	prefs.SetString("grblPort", s.GRBLPort)
+	prefs.SetString("pendantPort", s.PendantPort)
+	prefs.SetString("fileRoot", s.FileRoot)
+	prefs.SetString("fileExtensions", strings.Join(s.FileExtensions, ","))
+	prefs.SetInt("windowWidth", s.WindowWidth)
+	prefs.SetInt("windowHeight", s.WindowHeight)
+}
+
+// GRBLMatcher returns a matcher for the configured GRBL device.
+func (s Settings) GRBLMatcher() spjs.SerialPortMatcher {
+	return deviceMatcher(s.GRBLVID, s.GRBLPID, s.GRBLSerial, s.GRBLPort)
+}
+
+// PendantMatcher returns a matcher for the configured pendant device.
+func (s Settings) PendantMatcher() spjs.SerialPortMatcher {
+	return deviceMatcher(s.PendantVID, s.PendantPID, s.PendantSerial, s.PendantPort)
+}
+
+func deviceMatcher(vid, pid, serial, port string) spjs.SerialPortMatcher {
+	m := spjs.NewVIDPIDMatcher(vid, pid)
+	if serial != "" {
+		m = spjs.NewAndMatcher(m, spjs.NewSerialNumberMatcher(serial))
+	}
+	if port != "" {
+		expr, err := regexp.Compile(port)
+		if err != nil {
+			log.Printf("ERROR: invalid port expression '%s': %v", port, err)
+		} else {
+			m = spjs.NewAndMatcher(m, spjs.NewNameMatcher(expr))
+		}
+	}
+	return m
+}
+
+// NewSettingsEditor returns a form for changing settings. Saved settings are stored and passed to `apply`.
+func NewSettingsEditor(prefs fyne.Preferences, cli *spjs.Client, w fyne.Window, apply func(Settings)) fyne.CanvasObject {
+	cur := LoadSettings(prefs)
+
+	newEntry := func(val string) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetText(val)
+		return e
+	}
+
+	grblVID := newEntry(cur.GRBLVID)
+	grblPID := newEntry(cur.GRBLPID)
+	pendantVID := newEntry(cur.PendantVID)
+	pendantPID := newEntry(cur.PendantPID)
+	grblSerial := newEntry(cur.GRBLSerial)
+	pendantSerial := newEntry(cur.PendantSerial)
+	grblPort := newEntry(cur.GRBLPort)
+	pendantPort := newEntry(cur.PendantPort)
+	fileRoot := newEntry(cur.FileRoot)
+	fileExt := newEntry(strings.Join(cur.FileExtensions, ","))
+	width := newEntry(strconv.Itoa(cur.WindowWidth))
+	height := newEntry(strconv.Itoa(cur.WindowHeight))
+
+	pair := func(a, b fyne.CanvasObject) fyne.CanvasObject {
+		return fyne.NewContainerWithLayout(layout.NewGridLayout(2), a, b)
+	}
+
+	// pickDevice will fill in the entries from one of the serial ports currently listed by SPJS
+	pickDevice := func(role string, vid, pid, serial, port *widget.Entry) fyne.CanvasObject {
+		return widget.NewButton("Pick", func() {
+			ports := cli.SerialPorts()
+			if len(ports) == 0 {
+				dialog.ShowInformation("No Devices", "No serial ports are available from SPJS.", w)
+				return
+			}
+
+			list := widget.NewVBox()
+			var d dialog.Dialog
+			for _, sp := range ports {
+				sp := sp
+				label := fmt.Sprintf("%s (%s:%s)", sp.Name, sp.VID, sp.PID)
+				if sp.Friendly != "" {
+					label = sp.Friendly + " - " + label
+				}
+				if sp.SerialNumber != "" {
+					label += " #" + sp.SerialNumber
+				}
+				list.Append(widget.NewButton(label, func() {
+					vid.SetText(sp.VID)
+					pid.SetText(sp.PID)
+					serial.SetText(sp.SerialNumber)
+					port.SetText("")
+					if sp.SerialNumber == "" {
+						// devices without a serial number can only be told apart by where they are plugged in
+						port.SetText("^" + regexp.QuoteMeta(sp.Name) + "$")
+					}
+					d.Hide()
+				}))
+			}
+			d = dialog.NewCustom("Select "+role+" Device", "Cancel", widget.NewVScrollContainer(list), w)
+			d.Resize(fyne.NewSize(w.Canvas().Size().Width*3/4, w.Canvas().Size().Height*3/4))
+			d.Show()
+		})
+	}
+	device := func(role string, vid, pid, serial, port *widget.Entry) fyne.CanvasObject {
+		serial.SetPlaceHolder("Any Serial #")
+		port.SetPlaceHolder("Any Port")
+		btn := pickDevice(role, vid, pid, serial, port)
+		return fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, btn), btn,
+			fyne.NewContainerWithLayout(layout.NewGridLayout(4), vid, pid, serial, port),
+		)
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("GRBL Device", device("GRBL", grblVID, grblPID, grblSerial, grblPort)),
+		widget.NewFormItem("Pendant Device", device("Pendant", pendantVID, pendantPID, pendantSerial, pendantPort)),
+		widget.NewFormItem("Job Folder", fileRoot),
+		widget.NewFormItem("Job Extensions", fileExt),
+		widget.NewFormItem("Window Size", pair(width, height)),
+	)
+
+	save := widget.NewButton("Save", func() {
+		s := Settings{
+			GRBLVID:    strings.TrimSpace(grblVID.Text),
+			GRBLPID:    strings.TrimSpace(grblPID.Text),
+			PendantVID: strings.TrimSpace(pendantVID.Text),
+			PendantPID: strings.TrimSpace(pendantPID.Text),
+			FileRoot:   strings.TrimSpace(fileRoot.Text),
+
+			GRBLSerial:    strings.TrimSpace(grblSerial.Text),
+			PendantSerial: strings.TrimSpace(pendantSerial.Text),
+			GRBLPort:      strings.TrimSpace(grblPort.Text),
+			PendantPort:   strings.TrimSpace(pendantPort.Text),
+		}
+		for _, ext := range strings.Split(fileExt.Text, ",") {
+			ext = strings.TrimSpace(ext)
+			if ext == "" {
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			s.FileExtensions = append(s.FileExtensions, ext)
+		}
+
+		var err error
+		s.WindowWidth, err = strconv.Atoi(strings.TrimSpace(width.Text))
+		if err != nil || s.WindowWidth <= 0 {
+			dialog.ShowError(fmt.Errorf("invalid window width '%s'", width.Text), w)
+			return
+		}
+		s.WindowHeight, err = strconv.Atoi(strings.TrimSpace(height.Text))
+		if err != nil || s.WindowHeight <= 0 {
+			dialog.ShowError(fmt.Errorf("invalid window height '%s'", height.Text), w)
+			return
+		}
+		if s.GRBLVID == "" || s.GRBLPID == "" || s.PendantVID == "" || s.PendantPID == "" {
+			dialog.ShowError(errors.New("all VID/PID values are required"), w)
+			return
+		}
+		for _, expr := range []string{s.GRBLPort, s.PendantPort} {
+			_, err = regexp.Compile(expr)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid port expression '%s': %w", expr, err), w)
+				return
+			}
+		}
+
+		s.Save(prefs)
+		apply(s)
+	})
+	save.Importance = widget.HighImportance
+
+	return fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, save, nil, nil),
+		save, widget.NewVScrollContainer(form),
+	)
+}
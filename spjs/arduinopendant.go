@@ -3,6 +3,7 @@ package spjs
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"sync"
 	"time"
@@ -10,21 +11,59 @@ import (
 
 type ArduinoPendant struct {
 	ctrl *Controller
+	mpg  *mpgJogger
 
 	mx          sync.Mutex
 	lastMessage time.Time
+	proto       *PendantProtocol
+
+	display  bool
+	lastStep pendantStep
+	units    Units
+
+	lostCh chan PendantLost
+}
+
+// pendantTimeout is how long without a message before the pendant is considered lost.
+const pendantTimeout = 2 * time.Second
+
+// PendantLost describes the pendant going silent or being unplugged.
+type PendantLost struct {
+	Time   time.Time
+	Reason string
+
+	// Stopped is true if an MPG jog was in progress and was stopped.
+	Stopped bool
+	Err     error
 }
 
 var _ Driver = &ArduinoPendant{}
 
 // NewArduinoPendant will create a new pendant driver that will relay commands to the provided controller.
-func NewArduinoPendant(ctrl *Controller) *ArduinoPendant { return &ArduinoPendant{ctrl: ctrl} }
+//
+// It uses the DefaultPendantProtocol until SetProtocol is called.
+func NewArduinoPendant(ctrl *Controller) *ArduinoPendant {
+	return &ArduinoPendant{
+		ctrl:   ctrl,
+		mpg:    ctrl.jogger(),
+		proto:  DefaultPendantProtocol(),
+		lostCh: make(chan PendantLost, 1),
+	}
+}
 
 // Name always returns `ArduinoPendant`.
 func (p *ArduinoPendant) Name() string { return "ArduinoPendant" }
 
 // Connected returns true if the serial port is available and open.
-func (p *ArduinoPendant) Connected() bool { return time.Since(p.lastMessage) < 2*time.Second }
+func (p *ArduinoPendant) Connected() bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return time.Since(p.lastMessage) < pendantTimeout
+}
+
+// Lost will return a channel that gets a value each time the pendant is lost after being connected. Only the
+// latest value is kept. It always returns the same channel.
+func (p *ArduinoPendant) Lost() <-chan PendantLost { return p.lostCh }
 
 // BufferAlgorithm always returns `default` (no buffer).
 func (p *ArduinoPendant) BufferAlgorithm() string { return "default" }
@@ -32,42 +71,200 @@ func (p *ArduinoPendant) BufferAlgorithm() string { return "default" }
 // BaudRate always returns `115200`.
 func (p *ArduinoPendant) BaudRate() int { return 115200 }
 
-// SetPort has no effect as the pendant is read-only.
-func (p *ArduinoPendant) SetPort(*Port) {}
+// SetPort will start jogging from step messages, and sending DRO lines to the pendant once it announces it has a display.
+func (p *ArduinoPendant) SetPort(port *Port) {
+	go p.displayLoop(port)
+	go p.heartbeatLoop(port)
+}
+
+// heartbeatLoop will stop MPG motion as soon as the pendant goes silent or its port goes away, as it
+// may also be the operator's e-stop.
+func (p *ArduinoPendant) heartbeatLoop(port *Port) {
+	events := port.Events()
+	t := time.NewTicker(pendantTimeout / 8)
+	defer t.Stop()
+
+	var wasConnected bool
+	for {
+		reason := "no messages for " + pendantTimeout.String()
+		select {
+		case <-port.cli.ctx.Done():
+			return
+		case <-t.C:
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != PortClosed && e.Type != PortDisappeared && e.Type != PortReconnecting {
+				continue
+			}
+			reason = "port " + strings.ToLower(e.Type.String())
+			p.mx.Lock()
+			p.lastMessage = time.Time{}
+			p.mx.Unlock()
+		}
+
+		connected := p.Connected()
+		if connected || !wasConnected {
+			wasConnected = connected
+			continue
+		}
+		wasConnected = false
+
+		lost := PendantLost{Time: time.Now(), Reason: reason}
+		lost.Stopped, lost.Err = p.mpg.stop(port.cli.ctx, p)
+		switch {
+		case lost.Err != nil:
+			log.Printf("ERROR: pendant lost (%s), stop MPG jog: %v", reason, lost.Err)
+		case lost.Stopped:
+			log.Printf("WARN: pendant lost (%s), stopped MPG jog", reason)
+		default:
+			log.Printf("WARN: pendant lost (%s)", reason)
+		}
+
+		// only the latest is kept for the reader
+		select {
+		case <-p.lostCh:
+		default:
+		}
+		select {
+		case p.lostCh <- lost:
+		default:
+		}
+	}
+}
+
+// SetProtocol will change how pendant messages are interpreted.
+func (p *ArduinoPendant) SetProtocol(pp *PendantProtocol) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.proto = pp
+}
+
+// SetUnits will change the units of positions and step sizes sent to the pendant display.
+func (p *ArduinoPendant) SetUnits(u Units) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.units = u
+}
 
 // HandleData will process requests from the pendant and pass them to the controller.
+//
+// Messages are matched against the protocol buttons first, then the step pattern. Steps are
+// jogged by the MPG engine rather than sent one command per message.
 func (p *ArduinoPendant) HandleData(ctx context.Context, data string) error {
+	msg := strings.TrimSpace(data)
 	p.mx.Lock()
 	p.lastMessage = time.Now()
-	p.mx.Unlock()
-	if strings.TrimSpace(data) == "STOP" {
-		return p.ctrl.CommandEStop(ctx)
+	proto := p.proto
+	if proto.isDisplay(msg) {
+		p.display = true
 	}
+	p.mx.Unlock()
 
-	if !strings.HasPrefix(data, "STEP") {
+	if action, ok := proto.button(msg); ok {
+		// some actions wait for completion, which is delivered by the same loop calling HandleData
+		go func() {
+			err := runPendantAction(ctx, p.ctrl, action)
+			if err != nil {
+				log.Printf("ERROR: pendant action %s: %v", action, err)
+			}
+		}()
 		return nil
 	}
 
-	var axisIndex, mult, step int
-	_, err := fmt.Sscanf(data, "STEP:%d,%d,%d", &axisIndex, &mult, &step)
+	steps, ok, err := proto.parseStep(msg)
 	if err != nil {
 		return err
 	}
-
-	var axis rune
-	switch axisIndex {
-	case 1:
-		axis = 'X'
-	case 2:
-		axis = 'Y'
-	case 3:
-		axis = 'Z'
-
-		// invert Z
-		step = -step
-	default:
+	if !ok || len(steps) == 0 {
 		return nil
 	}
 
-	return p.ctrl.CommandJog(ctx, axis, float64(step)*float64(mult)/100, false)
+	p.mx.Lock()
+	p.lastStep = steps[0]
+	p.mx.Unlock()
+
+	p.mpg.add(p, steps)
+	return nil
+}
+
+// displayLoop will send a DRO line whenever it changes, no more often than the protocol allows.
+//
+// Lines are in the form `DRO:<state>;<axis>;<step>;<x>,<y>,<z>[,<a>,<b>,<c>]` with work positions and the
+// step size in the units set with SetUnits. Rotary axes are always in degrees.
+func (p *ArduinoPendant) displayLoop(port *Port) {
+	var last string
+	for {
+		p.mx.Lock()
+		interval := time.Duration(p.proto.DisplayMillis) * time.Millisecond
+		enabled := p.display && time.Since(p.lastMessage) < pendantTimeout
+		if !enabled {
+			// the pendant must announce its display again after reconnecting
+			p.display = false
+		}
+		step := p.lastStep
+		units := p.units
+		p.mx.Unlock()
+
+		t := time.NewTimer(interval)
+		select {
+		case <-port.cli.ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		if !enabled {
+			last = ""
+			continue
+		}
+		line := p.droLine(step, units)
+		if line == "" || line == last {
+			continue
+		}
+
+		err := port.sendQuiet(line)
+		if err != nil {
+			continue
+		}
+		last = line
+	}
+}
+
+func (p *ArduinoPendant) droLine(step pendantStep, units Units) string {
+	stat, ok := p.ctrl.CurrentStatus()
+	if !ok {
+		return ""
+	}
+
+	axis := "-"
+	if step.Axis != 0 {
+		axis = string(step.Axis)
+	}
+
+	// inches need an extra digit for the same resolution
+	valFmt := "%.3f"
+	if units == Inches {
+		valFmt = "%.4f"
+	}
+	convert := func(axis rune, mm float64) float64 {
+		if IsRotaryAxis(axis) {
+			return mm
+		}
+		return units.FromMM(mm)
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "DRO:%s;%s;"+valFmt+";", stat.StatusText(), axis, convert(step.Axis, step.Size))
+	pos := stat.WorkPosition()
+	for i := 0; i < stat.AxisCount(); i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		a := rune(Axes[i])
+		fmt.Fprintf(&buf, valFmt, convert(a, pos.Axis(a)))
+	}
+	buf.WriteString("\n")
+	return buf.String()
 }
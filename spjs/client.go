@@ -5,7 +5,6 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
-	"fmt"
 	"io"
 	"log"
 	"sync"
@@ -14,9 +13,16 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// ErrClientClosed is returned when using a Client after it has been closed.
+var ErrClientClosed = errors.New("SPJS client closed")
+
 type Client struct {
 	baseID string
 
+	ctx      context.Context
+	cancelFn func()
+	wg       sync.WaitGroup
+
 	id uint32
 
 	url string
@@ -29,6 +35,16 @@ type Client struct {
 	dataCh      chan string
 
 	callbacks chan callbackMap
+
+	eventSubs chan []eventSub
+
+	badMsgCh                 chan BadMessage
+	badMsgMx                 sync.Mutex
+	unknownMsgs, invalidMsgs int
+
+	connStatus   chan ConnStatus
+	connStatusCh chan ConnStatus
+	disconnectCh chan struct{}
 }
 type callbackMap map[commandID]*commandCallback
 
@@ -49,15 +65,22 @@ func (cb *commandCallback) written() {
 	}
 	close(cb.WriteCh)
 }
+
+// wasWritten returns true if SPJS reported the command as written to the serial port.
+func (cb *commandCallback) wasWritten() bool {
+	select {
+	case <-cb.WriteCh:
+		return true
+	default:
+		return false
+	}
+}
 func (cb *commandCallback) finish(err error) {
 	if cb == nil {
 		return
 	}
-	cb.Err = err
 	cb.once.Do(func() {
-		if cb.Err == nil {
-			cb.Err = err
-		}
+		cb.Err = err
 		close(cb.DoneCh)
 	})
 }
@@ -82,7 +105,11 @@ type SPJSCmdData struct {
 	Buf  bool   `json:"Buf,omitempty"`
 }
 
-func NewClient(url string) *Client {
+// NewClient will create a new SPJS client that runs until `Close` is called.
+func NewClient(url string) *Client { return NewClientContext(context.Background(), url) }
+
+// NewClientContext will create a new SPJS client that runs until `Close` is called or the context is canceled.
+func NewClientContext(ctx context.Context, url string) *Client {
 	buf := make([]byte, 8)
 	_, err := io.ReadFull(rand.Reader, buf)
 	if err != nil {
@@ -96,33 +123,95 @@ func NewClient(url string) *Client {
 		callbacks:   make(chan callbackMap, 1),
 		dataCh:      make(chan string),
 		ports:       make(chan []*Port, 1),
+		eventSubs:   make(chan []eventSub, 1),
+
+		connStatus:   make(chan ConnStatus, 1),
+		connStatusCh: make(chan ConnStatus, 1),
+		disconnectCh: make(chan struct{}, 1),
+		badMsgCh:     make(chan BadMessage, 10),
 	}
 
 	cli.serialPorts <- nil
 	cli.ports <- nil
+	cli.eventSubs <- nil
+	cli.connStatus <- ConnStatus{Since: time.Now()}
 	cli.callbacks <- make(callbackMap)
+	cli.ctx, cli.cancelFn = context.WithCancel(ctx)
 
+	cli.wg.Add(3)
 	// update port list
-	go func() {
-		for range time.NewTicker(10 * time.Second).C {
-			io.WriteString(cli, "list")
+	go cli.every(10*time.Second, func() { io.WriteString(cli, "list") })
+	go cli.connectLoop()
+
+	// process messages
+	go cli.readLoop(cli.ctx)
+
+	return cli
+}
+
+// every will call fn at the provided interval until the client is closed.
+func (c *Client) every(interval time.Duration, fn func()) {
+	defer c.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			fn()
+		case <-c.ctx.Done():
+			return
 		}
-	}()
+	}
+}
 
-	go func() {
-		for range time.NewTicker(time.Second).C {
-			cli.Check()
+// Close will stop all background processing, close the SPJS connection, and fail any pending commands.
+//
+// It waits for background goroutines to finish, or until the context is canceled.
+func (c *Client) Close(ctx context.Context) error {
+	c.cancelFn()
+
+	c.mx.Lock()
+	if c.ws != nil {
+		c.ws.Close()
+		c.ws = nil
+	}
+	c.mx.Unlock()
+
+	c.withCallbacks(func(m callbackMap) {
+		for id, cb := range m {
+			cb.finish(ErrClientClosed)
+			delete(m, id)
 		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
 	}()
 
-	// process messages
-	go cli.readLoop(context.TODO())
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	return cli
+	// no more events will be sent once background processing has stopped
+	subs := <-c.eventSubs
+	for _, sub := range subs {
+		close(sub.ch)
+	}
+	c.eventSubs <- nil
+
+	return nil
 }
 
 func (c *Client) NewPort(match SerialPortMatcher, drv Driver) *Port {
-	p := &Port{match: match, cli: c, drv: drv, sendCh: make(chan *sendReq, 1000)}
+	p := &Port{match: match, cli: c, drv: drv, sendCh: make(chan *sendReq, 1000), logCh: make(chan PortLogEntry, 100), statsCh: make(chan struct{}, 1)}
+	if s, ok := drv.(PortSetter); ok {
+		s.SetPort(p)
+	}
+	c.wg.Add(1)
 	go p.sendLoop()
 	c.ports <- append(<-c.ports, p)
 	io.WriteString(c, "list")
@@ -145,101 +234,3 @@ func (c *Client) withCallbacks(update func(callbackMap)) {
 	update(cbMap)
 	c.callbacks <- cbMap
 }
-
-func (c *Client) reconnect() error {
-	log.Println("Connecting to:", c.url)
-	cleanup := func() {
-		<-c.serialPorts
-		c.serialPorts <- nil
-		c.ws.Close()
-		c.ws = nil
-
-		err := errors.New("NETWORK ERROR")
-		c.withCallbacks(func(m callbackMap) {
-			for id, cb := range m {
-				cb.finish(err)
-				delete(m, id)
-			}
-		})
-	}
-	if c.ws != nil {
-		cleanup()
-	}
-
-	var err error
-
-	ws, _, err := websocket.DefaultDialer.Dial(c.url, nil)
-	if err != nil {
-		return fmt.Errorf("dial SPJS: %w", err)
-	}
-
-	err = ws.WriteMessage(websocket.TextMessage, []byte("list"))
-	if err != nil {
-		ws.Close()
-		return fmt.Errorf("write SPJS (list): %w", err)
-	}
-
-	c.ws = ws
-
-	go func() {
-		for {
-			_, data, err := ws.ReadMessage()
-			if err != nil {
-				log.Printf("ERROR: read SPJS: %v", err)
-				break
-			}
-
-			c.dataCh <- string(data)
-		}
-
-		c.mx.Lock()
-		if c.ws != nil {
-			cleanup()
-		}
-		c.mx.Unlock()
-	}()
-
-	return nil
-}
-
-func (c *Client) Check() error {
-	c.mx.Lock()
-	defer c.mx.Unlock()
-
-	var err error
-	if c.ws == nil {
-		err = c.reconnect()
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// Write will write to the active ws stream, reconnecting on error.
-func (c *Client) Write(p []byte) (int, error) {
-	c.mx.Lock()
-	defer c.mx.Unlock()
-
-	var err error
-	if c.ws == nil {
-		err = c.reconnect()
-		if err != nil {
-			return 0, err
-		}
-	}
-
-	// log.Println("WRITE:", string(p))
-	err = c.ws.WriteMessage(websocket.TextMessage, p)
-	if err != nil {
-		log.Println("ERROR: write SPJS (will reconnect): %w", err)
-		err = c.reconnect()
-		if err != nil {
-			return 0, err
-		}
-		return c.Write(p)
-	}
-
-	return len(p), nil
-}
@@ -0,0 +1,310 @@
+package spjs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// spjsPingInterval is how often a ping is sent to SPJS to check the connection.
+	spjsPingInterval = 10 * time.Second
+
+	// spjsReadTimeout is how long to wait for any message (including pongs) before the connection is considered dead.
+	spjsReadTimeout = 25 * time.Second
+
+	// spjsWriteTimeout is the max time allowed to write a single message.
+	spjsWriteTimeout = 5 * time.Second
+
+	spjsMinBackoff = 500 * time.Millisecond
+	spjsMaxBackoff = 30 * time.Second
+
+	// spjsStableAfter is how long a connection must stay up before the backoff is reset, so a server
+	// that accepts and then drops connections isn't redialed in a tight loop.
+	spjsStableAfter = 10 * time.Second
+)
+
+// ErrNotConnected is returned when writing while there is no connection to SPJS.
+var ErrNotConnected = errors.New("not connected to SPJS")
+
+// ErrConnectionLost is the result of any command that was pending when the SPJS connection was lost.
+var ErrConnectionLost = errors.New("NETWORK ERROR")
+
+// ConnState is the state of the websocket connection to SPJS.
+type ConnState int
+
+const (
+	ConnDisconnected ConnState = iota
+	ConnConnecting
+	ConnConnected
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnDisconnected:
+		return "Disconnected"
+	case ConnConnecting:
+		return "Connecting"
+	case ConnConnected:
+		return "Connected"
+	}
+	return "Unknown"
+}
+
+// ConnStatus describes the current connection to SPJS.
+type ConnStatus struct {
+	State ConnState
+	Since time.Time
+
+	// LastErr is the reason the last connection attempt failed, or the last connection was lost.
+	LastErr error
+
+	// Failures is the number of connection attempts that have failed since the last successful connection.
+	Failures int
+}
+
+// LastConnStatus returns the current SPJS connection status.
+func (c *Client) LastConnStatus() ConnStatus {
+	stat := <-c.connStatus
+	c.connStatus <- stat
+	return stat
+}
+
+// ConnStatus returns a channel that will get the latest status each time the SPJS connection changes. It always returns the same channel.
+func (c *Client) ConnStatus() <-chan ConnStatus { return c.connStatusCh }
+
+// Check returns nil if connected to SPJS, otherwise the reason for the last failure.
+func (c *Client) Check() error {
+	if c.ctx.Err() != nil {
+		return ErrClientClosed
+	}
+	stat := c.LastConnStatus()
+	if stat.State == ConnConnected {
+		return nil
+	}
+	if stat.LastErr != nil {
+		return stat.LastErr
+	}
+	return ErrNotConnected
+}
+
+func (c *Client) setConnState(state ConnState, err error) {
+	stat := <-c.connStatus
+	if state != stat.State {
+		stat.Since = time.Now()
+	}
+	stat.State = state
+	switch {
+	case state == ConnConnected:
+		stat.Failures = 0
+	case err != nil:
+		stat.LastErr = err
+		if state == ConnDisconnected {
+			stat.Failures++
+		}
+	}
+	c.connStatus <- stat
+
+	// only the latest status is kept for the reader
+	select {
+	case <-c.connStatusCh:
+	default:
+	}
+	select {
+	case c.connStatusCh <- stat:
+	default:
+	}
+}
+
+// connectLoop will keep a connection to SPJS open, retrying with exponential backoff until the client is closed.
+func (c *Client) connectLoop() {
+	defer c.wg.Done()
+
+	backoff := spjsMinBackoff
+	for {
+		err := c.connect()
+		if err == nil {
+			connected := time.Now()
+			select {
+			case <-c.disconnectCh:
+			case <-c.ctx.Done():
+				return
+			}
+			if time.Since(connected) >= spjsStableAfter {
+				backoff = spjsMinBackoff
+				continue
+			}
+			// the disconnect was already reported, wait out the backoff before dialing again
+		} else {
+			if c.ctx.Err() != nil {
+				return
+			}
+			log.Println("ERROR:", err)
+			c.setConnState(ConnDisconnected, err)
+		}
+
+		// wait between half and the full backoff so multiple clients don't retry in lockstep
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-c.ctx.Done():
+			t.Stop()
+			return
+		}
+
+		backoff *= 2
+		if backoff > spjsMaxBackoff {
+			backoff = spjsMaxBackoff
+		}
+	}
+}
+
+func (c *Client) connect() error {
+	log.Println("Connecting to:", c.url)
+	c.setConnState(ConnConnecting, nil)
+
+	ws, _, err := websocket.DefaultDialer.DialContext(c.ctx, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("dial SPJS: %w", err)
+	}
+
+	ws.SetReadDeadline(time.Now().Add(spjsReadTimeout))
+	ws.SetPongHandler(func(string) error { return ws.SetReadDeadline(time.Now().Add(spjsReadTimeout)) })
+
+	c.mx.Lock()
+	if c.ctx.Err() != nil {
+		c.mx.Unlock()
+		ws.Close()
+		return ErrClientClosed
+	}
+	ws.SetWriteDeadline(time.Now().Add(spjsWriteTimeout))
+	err = ws.WriteMessage(websocket.TextMessage, []byte("list"))
+	if err != nil {
+		c.mx.Unlock()
+		ws.Close()
+		return fmt.Errorf("write SPJS (list): %w", err)
+	}
+	c.ws = ws
+	c.mx.Unlock()
+
+	c.setConnState(ConnConnected, nil)
+
+	done := make(chan struct{})
+	c.wg.Add(2)
+	go c.readWS(ws, done)
+	go c.pingWS(ws, done)
+
+	return nil
+}
+
+// readWS will pass messages to the read loop until the connection fails.
+func (c *Client) readWS(ws *websocket.Conn, done chan struct{}) {
+	defer c.wg.Done()
+	defer close(done)
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			log.Printf("ERROR: read SPJS: %v", err)
+			c.disconnect(ws, fmt.Errorf("read SPJS: %w", err))
+			return
+		}
+		ws.SetReadDeadline(time.Now().Add(spjsReadTimeout))
+
+		select {
+		case c.dataCh <- string(data):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// pingWS will periodically ping SPJS so that a dead connection causes a read timeout.
+func (c *Client) pingWS(ws *websocket.Conn, done chan struct{}) {
+	defer c.wg.Done()
+
+	t := time.NewTicker(spjsPingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+
+		err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(spjsWriteTimeout))
+		if err != nil {
+			c.disconnect(ws, fmt.Errorf("ping SPJS: %w", err))
+			return
+		}
+	}
+}
+
+// disconnect will close the connection and fail all pending commands, if `ws` is still the active connection.
+func (c *Client) disconnect(ws *websocket.Conn, reason error) {
+	c.mx.Lock()
+	if c.ws != ws || c.ctx.Err() != nil {
+		// already replaced, or handled by Close
+		c.mx.Unlock()
+		return
+	}
+	c.ws = nil
+	ws.Close()
+	c.mx.Unlock()
+
+	<-c.serialPorts
+	c.serialPorts <- nil
+
+	ports := <-c.ports
+	c.ports <- ports
+	for _, p := range ports {
+		p.resetState(reason.Error())
+	}
+
+	c.withCallbacks(func(m callbackMap) {
+		for id, cb := range m {
+			cb.finish(ErrConnectionLost)
+			delete(m, id)
+		}
+	})
+
+	c.setConnState(ConnDisconnected, reason)
+	select {
+	case c.disconnectCh <- struct{}{}:
+	default:
+	}
+}
+
+// Write will write to the active ws stream. If the write fails the connection is re-established in the background.
+func (c *Client) Write(p []byte) (int, error) {
+	c.mx.Lock()
+	if c.ctx.Err() != nil {
+		c.mx.Unlock()
+		return 0, ErrClientClosed
+	}
+	ws := c.ws
+	if ws == nil {
+		c.mx.Unlock()
+		return 0, ErrNotConnected
+	}
+
+	// log.Println("WRITE:", string(p))
+	ws.SetWriteDeadline(time.Now().Add(spjsWriteTimeout))
+	err := ws.WriteMessage(websocket.TextMessage, p)
+	c.mx.Unlock()
+	if err != nil {
+		err = fmt.Errorf("write SPJS: %w", err)
+		log.Println("ERROR:", err)
+		c.disconnect(ws, err)
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 	"sync"
+	"time"
 )
 
 type jobAction int
@@ -22,21 +24,29 @@ type Controller struct {
 	mx        sync.Mutex
 	job       *jobController
 	jobStatus chan JobStatus
+	lastKick  time.Time
 
-	wrapGCode func([]string) string
+	mpgOnce sync.Once
+	mpg     *mpgJogger
 }
 
 func (p *Port) NewController() *Controller {
-	w, ok := p.drv.(GCodeWrapper)
 	c := &Controller{Port: p, jobStatus: make(chan JobStatus, 1)}
-	if ok {
-		c.wrapGCode = w.WrapGCode
-	}
+	go c.watchdog()
 	return c
 }
 
+// wrapGCode will prepare job lines for sending, the driver may have changed since the job was started.
+func (c *Controller) wrapGCode(data []string) string {
+	w, ok := c.Driver().(GCodeWrapper)
+	if !ok {
+		return strings.Join(data, "\n") + "\n"
+	}
+	return w.WrapGCode(data)
+}
+
 func (c *Controller) SetJob(name string, r io.Reader) error {
-	if c.wrapGCode == nil {
+	if _, ok := c.Driver().(GCodeWrapper); !ok {
 		return ErrUnsupportedByDriver
 	}
 
@@ -47,14 +57,14 @@ func (c *Controller) SetJob(name string, r io.Reader) error {
 		c.job.Close()
 	}
 
-	c.job = newJobController(context.Background(), c, name, r)
+	c.job = newJobController(c.cli.ctx, c, name, r)
 
 	return nil
 }
 func (c *Controller) JobStatus() <-chan JobStatus { return c.jobStatus }
 
 func (c *Controller) Status() <-chan ControllerStatus {
-	s, ok := c.drv.(Statusable)
+	s, ok := c.Driver().(Statusable)
 	if !ok {
 		return nil
 	}
@@ -62,6 +72,33 @@ func (c *Controller) Status() <-chan ControllerStatus {
 	return s.Status()
 }
 
+// CurrentStatus returns the latest controller status, ok is false if it is not available yet.
+func (c *Controller) CurrentStatus() (stat ControllerStatus, ok bool) {
+	s, ok := c.Driver().(StatusGetter)
+	if !ok {
+		return nil, false
+	}
+
+	return s.CurrentStatus()
+}
+
+// StatusAge returns the time since the controller last reported its status.
+func (c *Controller) StatusAge() (time.Duration, error) {
+	s, ok := c.Driver().(StatusAger)
+	if !ok {
+		return 0, ErrUnsupportedByDriver
+	}
+
+	return s.StatusAge(), nil
+}
+
+// StatusDeferred returns true if status requests are being held back by the driver, so the status is
+// expected to be out of date. It is always false for drivers that poll regardless.
+func (c *Controller) StatusDeferred() bool {
+	s, ok := c.Driver().(StatusDeferrer)
+	return ok && s.StatusDeferred()
+}
+
 func (c *Controller) StartJob(ctx context.Context) error {
 	c.mx.Lock()
 	defer c.mx.Unlock()
@@ -74,7 +111,7 @@ func (c *Controller) StartJob(ctx context.Context) error {
 }
 
 func (c *Controller) CommandCycleStart(ctx context.Context) error {
-	s, ok := c.drv.(CycleStarter)
+	s, ok := c.Driver().(CycleStarter)
 	if !ok {
 		return ErrUnsupportedByDriver
 	}
@@ -82,7 +119,7 @@ func (c *Controller) CommandCycleStart(ctx context.Context) error {
 	return c.SendCommand(ctx, s.CycleStart(), false)
 }
 func (c *Controller) CommandReset(ctx context.Context) error {
-	f, ok := c.drv.(Resetter)
+	f, ok := c.Driver().(Resetter)
 	if !ok {
 		return ErrUnsupportedByDriver
 	}
@@ -101,7 +138,7 @@ func (c *Controller) CommandReset(ctx context.Context) error {
 	return c.SendCommand(ctx, f.Reset(), false)
 }
 func (c *Controller) CommandFeedHold(ctx context.Context) error {
-	f, ok := c.drv.(FeedHolder)
+	f, ok := c.Driver().(FeedHolder)
 	if !ok {
 		return ErrUnsupportedByDriver
 	}
@@ -110,7 +147,7 @@ func (c *Controller) CommandFeedHold(ctx context.Context) error {
 }
 
 func (c *Controller) CommandHome(ctx context.Context, wait bool) error {
-	h, ok := c.drv.(Homeable)
+	h, ok := c.Driver().(Homeable)
 	if !ok {
 		return ErrUnsupportedByDriver
 	}
@@ -118,39 +155,138 @@ func (c *Controller) CommandHome(ctx context.Context, wait bool) error {
 }
 
 func (c *Controller) CommandEStop(ctx context.Context) error {
-	s, ok := c.drv.(EStopable)
+	s, ok := c.Driver().(EStopable)
 	if !ok {
 		return ErrUnsupportedByDriver
 	}
 	return c.SendCommand(ctx, s.EStop(), false)
 }
 
-// CommandJog issues a jog command and waits for it to finish.
+// CommandJog issues a jog command along a single axis.
 func (c *Controller) CommandJog(ctx context.Context, axis rune, mm float64, wait bool) error {
-	j, ok := c.drv.(Joggable)
+	return c.CommandJogAxes(ctx, []AxisMove{{Axis: axis, MM: mm}}, wait)
+}
+
+// CommandJogAxes issues a single jog command that moves all provided axes together.
+func (c *Controller) CommandJogAxes(ctx context.Context, moves []AxisMove, wait bool) error {
+	j, ok := c.Driver().(Joggable)
+	if !ok {
+		return ErrUnsupportedByDriver
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+	return c.SendCommand(ctx, j.Jog(moves...), wait)
+}
+
+// CommandJogFeed issues a single jog command at the provided feed rate (mm/min). If the driver can't set the
+// jog feed rate, it is the same as CommandJogAxes.
+func (c *Controller) CommandJogFeed(ctx context.Context, moves []AxisMove, feed float64, wait bool) error {
+	j, ok := c.Driver().(FeedJoggable)
+	if !ok {
+		return c.CommandJogAxes(ctx, moves, wait)
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+	return c.SendCommand(ctx, j.JogFeed(feed, moves...), wait)
+}
+
+// CommandStatusQuery will request a status report, the same as the status poll but logged.
+func (c *Controller) CommandStatusQuery(ctx context.Context) error {
+	q, ok := c.Driver().(StatusQuerier)
+	if !ok {
+		return ErrUnsupportedByDriver
+	}
+	return c.SendCommand(ctx, q.StatusQuery(), false)
+}
+
+// CommandJogCancel will stop any jog in progress.
+func (c *Controller) CommandJogCancel(ctx context.Context) error {
+	j, ok := c.Driver().(JogCanceler)
+	if !ok {
+		return ErrUnsupportedByDriver
+	}
+	return c.SendCommand(ctx, j.JogCancel(), false)
+}
+
+// CommandSpindleOn will start the spindle at the provided speed.
+func (c *Controller) CommandSpindleOn(ctx context.Context, rpm float64, ccw bool) error {
+	s, ok := c.Driver().(Spindler)
 	if !ok {
 		return ErrUnsupportedByDriver
 	}
-	return c.SendCommand(ctx, j.Jog(axis, mm), wait)
+	return c.SendCommand(ctx, s.SpindleOn(rpm, ccw), false)
 }
 
-// SetWPos will set the work coordinate to the proveded value.
+// CommandSpindleOff will stop the spindle.
+func (c *Controller) CommandSpindleOff(ctx context.Context) error {
+	s, ok := c.Driver().(Spindler)
+	if !ok {
+		return ErrUnsupportedByDriver
+	}
+	return c.SendCommand(ctx, s.SpindleOff(), false)
+}
+
+// CommandToggleFlood will toggle flood coolant on or off.
+func (c *Controller) CommandToggleFlood(ctx context.Context) error {
+	t, ok := c.Driver().(CoolantToggler)
+	if !ok {
+		return ErrUnsupportedByDriver
+	}
+	return c.SendCommand(ctx, t.ToggleFlood(), false)
+}
+
+// CommandToggleMist will toggle mist coolant on or off.
+func (c *Controller) CommandToggleMist(ctx context.Context) error {
+	t, ok := c.Driver().(CoolantToggler)
+	if !ok {
+		return ErrUnsupportedByDriver
+	}
+	return c.SendCommand(ctx, t.ToggleMist(), false)
+}
+
+// SetWPos will set the work coordinate to the proveded value (in mm, or degrees for rotary axes).
 func (c *Controller) SetWPos(ctx context.Context, axis rune, mm float64) error {
-	w, ok := c.drv.(WPosable)
+	drv := c.Driver()
+	w, ok := drv.(WPosable)
 	if !ok {
 		return ErrUnsupportedByDriver
 	}
+	if q, ok := drv.(ParserStateQuerier); ok {
+		err := c.SendCommand(ctx, q.ParserStateQuery(), true)
+		if err != nil {
+			return err
+		}
+	}
 	return c.SendCommand(ctx, w.WPos(axis, mm), true)
 }
 
+// AccessoryStatus is the state of the spindle and coolant outputs.
+type AccessoryStatus struct {
+	SpindleEnabled bool
+	SpindleCCW     bool
+	SpindleSpeed   float64
+
+	Flood bool
+	Mist  bool
+}
+
 type ControllerStatus interface {
 	MachinePosition() Position
 	WorkPosition() Position
 
 	StatusText() string
 
+	// FeedRate returns the current feed rate in mm/min.
+	FeedRate() float64
+
+	// Accessories returns the current spindle and coolant state.
+	Accessories() AccessoryStatus
+
+	// AxisCount returns the number of axes reported by the controller.
+	AxisCount() int
+
 	IsReady() bool
 	IsAlarm() bool
 }
-
-type Position struct{ X, Y, Z float64 }
@@ -0,0 +1,187 @@
+package spjs
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// detectBannerWait is how long to wait for a startup banner before sending identification commands.
+	detectBannerWait = 2 * time.Second
+
+	// detectSettle is how long to wait after a match for a higher priority rule to match.
+	detectSettle = 500 * time.Millisecond
+
+	// detectRetry is how long to wait for a response to identification commands before trying again.
+	detectRetry = 5 * time.Second
+)
+
+// DriverRule selects a driver when a line from the controller matches.
+type DriverRule struct {
+	Name  string
+	Match *regexp.Regexp
+	New   func() Driver
+}
+
+// driverRules are checked in order, earlier rules take priority if more than one matches.
+var driverRules = []DriverRule{
+	{Name: "grblHAL", Match: regexp.MustCompile(`^GrblHAL |^\[FIRMWARE:grblHAL\]`), New: func() Driver { return NewGRBLHAL() }},
+	{Name: "Smoothie", Match: regexp.MustCompile(`^Smoothie|FIRMWARE_NAME:Smoothieware|^Build version:`), New: func() Driver { return NewSmoothie() }},
+	{Name: "GRBL", Match: regexp.MustCompile(`^Grbl \d|^\[VER:`), New: func() Driver { return NewGRBL() }},
+	{Name: "Marlin", Match: regexp.MustCompile(`FIRMWARE_NAME:Marlin`), New: func() Driver { return NewMarlin() }},
+
+	// g2core build numbers are in the 100s, TinyG (v8) builds are in the 300s and 400s
+	{Name: "g2core", Match: regexp.MustCompile(`"fb":\s*1\d\d\.`), New: func() Driver { return NewG2Core() }},
+	{Name: "TinyG", Match: regexp.MustCompile(`"fb":\s*[2-9]\d\d\.`), New: func() Driver { return NewTinyG() }},
+}
+
+// detectProbes are sent if no banner is seen, each is harmless to firmware that doesn't understand it.
+var detectProbes = []string{"$I\n", "M115\n", `{"fb":null}` + "\n"}
+
+// RegisterDriver will add a detection rule, ahead of the built-in rules. It should be called before any ports are created.
+func RegisterDriver(rule DriverRule) {
+	driverRules = append([]DriverRule{rule}, driverRules...)
+}
+
+// Detector is a placeholder driver that identifies the controller firmware from its startup banner, or
+// by sending identification commands, and then replaces itself with the matching driver.
+type Detector struct {
+	port *Port
+	baud int
+
+	mx       sync.Mutex
+	best     int
+	settle   *time.Timer
+	detected bool
+}
+
+var _ Driver = &Detector{}
+
+// NewDetector returns a Detector that opens the port at the provided baud rate.
+func NewDetector(baud int) *Detector { return &Detector{baud: baud, best: -1} }
+
+// Name will always return the string `Detecting`.
+func (d *Detector) Name() string { return "Detecting" }
+
+// BufferAlgorithm returns the string `default`.
+func (d *Detector) BufferAlgorithm() string { return "default" }
+
+// BaudRate returns the baud rate provided to NewDetector.
+func (d *Detector) BaudRate() int { return d.baud }
+
+// SetPort will start probing each time the port is opened, until the firmware is detected.
+func (d *Detector) SetPort(p *Port) {
+	d.port = p
+	go func() {
+		var cancel func()
+		for e := range p.Events() {
+			if cancel != nil {
+				cancel()
+				cancel = nil
+			}
+			if d.isDetected() {
+				continue
+			}
+			if e.Type != PortOpened {
+				continue
+			}
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(p.cli.ctx)
+			go d.probe(ctx)
+		}
+		if cancel != nil {
+			cancel()
+		}
+	}()
+}
+
+func (d *Detector) isDetected() bool {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	return d.detected
+}
+
+func (d *Detector) hasMatch() bool {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	return d.best != -1
+}
+
+// probe will send identification commands until something matches or the context is canceled.
+func (d *Detector) probe(ctx context.Context) {
+	wait := detectBannerWait
+	for {
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if d.hasMatch() {
+			return
+		}
+
+		name, _ := d.port.Name()
+		log.Println("Identifying firmware on", name)
+		for _, cmd := range detectProbes {
+			err := d.port.SendCommand(ctx, cmd, false)
+			if err != nil {
+				log.Printf("ERROR: identify firmware (%s): %v", strings.TrimSpace(cmd), err)
+				break
+			}
+		}
+		wait = detectRetry
+	}
+}
+
+// HandleData will check each line against the detection rules. It is only intended to be used by the SPJS client code.
+func (d *Detector) HandleData(ctx context.Context, data string) error {
+	line := strings.TrimSpace(data)
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	if d.detected {
+		return nil
+	}
+
+	for i, rule := range driverRules {
+		if d.best != -1 && i >= d.best {
+			break
+		}
+		if !rule.Match.MatchString(line) {
+			continue
+		}
+
+		d.best = i
+		if d.settle == nil {
+			d.settle = time.AfterFunc(detectSettle, d.finish)
+		} else {
+			d.settle.Reset(detectSettle)
+		}
+		break
+	}
+
+	return nil
+}
+
+// finish will replace the Detector with the driver for the best matching rule. The port stays at the baud
+// rate the firmware was detected at, rather than the driver default.
+func (d *Detector) finish() {
+	d.mx.Lock()
+	if d.detected {
+		d.mx.Unlock()
+		return
+	}
+	d.detected = true
+	rule := driverRules[d.best]
+	d.mx.Unlock()
+
+	log.Printf("Detected %s firmware", rule.Name)
+	d.port.SetBaudRate(d.baud)
+	d.port.SetDriver(rule.New())
+}
@@ -0,0 +1,41 @@
+package spjs
+
+import "testing"
+
+func TestDriverRules(t *testing.T) {
+	match := func(line string) string {
+		for _, rule := range driverRules {
+			if rule.Match.MatchString(line) {
+				return rule.Name
+			}
+		}
+		return ""
+	}
+
+	for _, c := range []struct {
+		line, name string
+	}{
+		{"GrblHAL 1.1f ['$' or '$HELP' for help]", "grblHAL"},
+		{"[FIRMWARE:grblHAL]", "grblHAL"},
+		{"Smoothie", "Smoothie"},
+		{"Build version: edge-3332442, Build date: Nov 12 2018 20:09:02, MCU: LPC1769, System Clock: 120MHz", "Smoothie"},
+		{"FIRMWARE_NAME:Smoothieware, FIRMWARE_URL:http%3A//smoothieware.org, FIRMWARE_VERSION:edge-3332442, X-AXES:5", "Smoothie"},
+		{"Grbl 1.1h ['$' for help]", "GRBL"},
+		{"Grbl 0.9j ['$' for help]", "GRBL"},
+		{"[VER:1.1h.20190825:]", "GRBL"},
+		{"FIRMWARE_NAME:Marlin 2.0.9.3 (Feb 19 2022 12:00:00) SOURCE_CODE_URL:github.com/MarlinFirmware/Marlin PROTOCOL_VERSION:1.0 MACHINE_TYPE:3D Printer EXTRUDER_COUNT:1", "Marlin"},
+		{`{"r":{"fb":101.03},"f":[1,0,10]}`, "g2core"},
+		{`{"r":{"fb": 100.26},"f":[1,0,10]}`, "g2core"},
+		{`{"r":{"fb":440.20},"f":[1,0,9]}`, "TinyG"},
+		{`{"r":{"fb":380.08},"f":[1,0,9]}`, "TinyG"},
+
+		{"ok", ""},
+		{`echo:Unknown command: "$I"`, ""},
+		{"error:20", ""},
+		{`{"r":{},"f":[1,100,11]}`, ""},
+	} {
+		if got := match(c.line); got != c.name {
+			t.Errorf("%s: matched '%s'; want '%s'", c.line, got, c.name)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package spjs
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var ErrUnsupportedByDriver = errors.New("not supported by driver")
@@ -16,6 +17,9 @@ type Driver interface {
 	HandleData(context.Context, string) error
 }
 
+// PortSetter is implemented by drivers that need to send their own commands to the port.
+type PortSetter interface{ SetPort(*Port) }
+
 type FeedHolder interface{ FeedHold() string }
 type CycleStarter interface{ CycleStart() string }
 type Resetter interface{ Reset() string }
@@ -26,11 +30,50 @@ type GCodeWrapper interface {
 type Homeable interface{ Home() string }
 type EStopable interface{ EStop() string }
 type Joggable interface {
-	Jog(axis rune, mm float64) string
+	Jog(moves ...AxisMove) string
+}
+type FeedJoggable interface {
+	// JogFeed is the same as Jog, but moves at the provided feed rate (mm/min).
+	JogFeed(feed float64, moves ...AxisMove) string
 }
+type JogCanceler interface{ JogCancel() string }
 type WPosable interface {
 	WPos(axis rune, mm float64) string
 }
+
+// commandObserver is implemented by drivers that track modal state from the commands sent to the port.
+type commandObserver interface{ sent(command string) }
+
+// ParserStateQuerier is implemented by drivers that need the current modal state to build some
+// commands (e.g. WPos). The query is sent and completed first.
+type ParserStateQuerier interface{ ParserStateQuery() string }
+
+// StatusQuerier is implemented by drivers that request status reports with a command (e.g. `?`).
+type StatusQuerier interface{ StatusQuery() string }
+type Spindler interface {
+	SpindleOn(rpm float64, ccw bool) string
+	SpindleOff() string
+}
+type CoolantToggler interface {
+	ToggleFlood() string
+	ToggleMist() string
+}
+type BufferReporter interface {
+	// BufferFree returns the free planner blocks and serial RX bytes, ok is false if unknown.
+	BufferFree() (planner, rx int, ok bool)
+}
 type Statusable interface {
 	Status() <-chan ControllerStatus
 }
+type StatusGetter interface {
+	// CurrentStatus returns the latest status, ok is false if none has been received yet.
+	CurrentStatus() (stat ControllerStatus, ok bool)
+}
+type StatusAger interface {
+	// StatusAge returns the time since the last status report was received.
+	StatusAge() time.Duration
+}
+type StatusDeferrer interface {
+	// StatusDeferred returns true while status requests are held back, e.g. until queued commands finish.
+	StatusDeferred() bool
+}
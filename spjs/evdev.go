@@ -0,0 +1,143 @@
+package spjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Input event types, from linux/input-event-codes.h.
+const (
+	evKey = 1
+	evRel = 2
+	evAbs = 3
+)
+
+const evdevJogPrefix = "jog:"
+
+// EvdevBindings configure an EvdevInput.
+//
+// Event types and codes can be found with a tool like `evtest`.
+type EvdevBindings struct {
+	// Devices are glob patterns for input devices (e.g. `/dev/input/by-id/*-event-joystick`).
+	Devices []string `json:"devices"`
+
+	// Grab will take exclusive access to the devices, so key presses don't also reach the GUI.
+	Grab bool `json:"grab"`
+
+	Bindings []EvdevBinding `json:"bindings"`
+}
+
+// EvdevBinding runs an action for an input event.
+//
+// Actions are the same as pendant button actions, or `jog:<axis>` to jog by Step mm. Keys and absolute
+// axes (e.g. a d-pad or joystick) jog continuously while held, relative axes (e.g. a jog wheel) jog by
+// Step per unit. Absolute axes jog in the direction they are pushed from the centre of their range.
+type EvdevBinding struct {
+	// Type is one of `key`, `rel` or `abs`.
+	Type string `json:"type"`
+	Code uint16 `json:"code"`
+
+	// Value is the direction an `abs` event must have to match (e.g. -1 for d-pad left), or 0 for either.
+	Value int32 `json:"value"`
+
+	Action string  `json:"action"`
+	Step   float64 `json:"step"`
+
+	evType uint16
+}
+
+// EvdevInput jogs and runs controller commands from Linux input devices (`/dev/input/event*`),
+// such as USB gamepads, numeric keypads and wireless jog remotes.
+type EvdevInput struct {
+	ctrl *Controller
+	mpg  *mpgJogger
+	bind *EvdevBindings
+}
+
+// NewEvdevInput will create a new input that will relay events to the provided controller. It does nothing until Run is called.
+func NewEvdevInput(ctrl *Controller, b *EvdevBindings) *EvdevInput {
+	return &EvdevInput{ctrl: ctrl, mpg: ctrl.jogger(), bind: b}
+}
+
+// absRange is the range of an absolute axis, from `struct input_absinfo`.
+type absRange struct {
+	Min, Max int32
+
+	// Flat is the dead zone around the centre reported by the device, if any.
+	Flat int32
+}
+
+// direction returns -1 or 1 if the value is outside the dead zone around the centre of the range, otherwise 0.
+//
+// Without a reported dead zone a quarter of each half of the range is used, so joysticks that don't
+// rest exactly at the centre don't jog.
+func (r absRange) direction(v int32) int {
+	// doubled to keep the centre of odd ranges (e.g. 0-255) exact
+	centre := int64(r.Min) + int64(r.Max)
+	dz := 2 * int64(r.Flat)
+	if dz == 0 {
+		dz = (int64(r.Max) - int64(r.Min)) / 4
+	}
+
+	d := 2*int64(v) - centre
+	switch {
+	case d > dz:
+		return 1
+	case d < -dz:
+		return -1
+	}
+	return 0
+}
+
+// LoadEvdevBindings will read JSON input bindings.
+func LoadEvdevBindings(r io.Reader) (*EvdevBindings, error) {
+	var b EvdevBindings
+	err := json.NewDecoder(r).Decode(&b)
+	if err != nil {
+		return nil, fmt.Errorf("decode input bindings: %w", err)
+	}
+	if len(b.Devices) == 0 {
+		return nil, fmt.Errorf("no input devices")
+	}
+
+	for i, eb := range b.Bindings {
+		switch eb.Type {
+		case "key":
+			b.Bindings[i].evType = evKey
+		case "rel":
+			b.Bindings[i].evType = evRel
+		case "abs":
+			b.Bindings[i].evType = evAbs
+		default:
+			return nil, fmt.Errorf("binding %d: unknown type '%s'", i, eb.Type)
+		}
+
+		if axis := strings.TrimPrefix(eb.Action, evdevJogPrefix); axis != eb.Action {
+			if len(axis) != 1 || !strings.Contains(Axes, axis) {
+				return nil, fmt.Errorf("binding %d: unknown axis '%s'", i, axis)
+			}
+			if eb.Step == 0 {
+				return nil, fmt.Errorf("binding %d: jog step is required", i)
+			}
+			continue
+		}
+
+		err = validPendantAction(eb.Action)
+		if err != nil {
+			return nil, fmt.Errorf("binding %d: %w", i, err)
+		}
+	}
+
+	return &b, nil
+}
+
+// jogAxis returns the axis for a jog binding.
+func (eb EvdevBinding) jogAxis() (rune, bool) {
+	axis := strings.TrimPrefix(eb.Action, evdevJogPrefix)
+	if axis == eb.Action {
+		return 0, false
+	}
+	return rune(axis[0]), true
+}
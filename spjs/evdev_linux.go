@@ -0,0 +1,280 @@
+//go:build linux
+// +build linux
+
+package spjs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// evdevRescan is how often missing devices are looked for.
+	evdevRescan = 2 * time.Second
+
+	// evioCGrab is the EVIOCGRAB ioctl, _IOW('E', 0x90, int).
+	evioCGrab = 0x40044590
+
+	// evioCGAbs is the EVIOCGABS ioctl for axis 0, _IOR('E', 0x40+axis, struct input_absinfo).
+	evioCGAbs = 0x80184540
+)
+
+// inputEvent is `struct input_event` from linux/input.h.
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// evdevEvent is an input event along with the range of the axis, for absolute events.
+type evdevEvent struct {
+	inputEvent
+	abs absRange
+}
+
+// Run will read events from all matching devices until the context is canceled. Devices that are unplugged
+// stop any jog in progress, and are re-opened when they come back.
+func (in *EvdevInput) Run(ctx context.Context) error {
+	open := make(map[string]bool)
+	held := make(map[int]int) // binding index to direction, for jogs that repeat while held
+
+	t := time.NewTicker(mpgSlice)
+	defer t.Stop()
+	scan := time.NewTicker(evdevRescan)
+	defer scan.Stop()
+
+	events := make(chan evdevEvent, 64)
+	lost := make(chan string)
+	for {
+		paths := in.missing(open)
+		for _, path := range paths {
+			f, err := os.Open(path)
+			if err != nil {
+				log.Printf("ERROR: open input device %s: %v", path, err)
+				continue
+			}
+			if in.bind.Grab {
+				err = ioctl(f, func(fd uintptr) syscall.Errno {
+					_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, evioCGrab, 1)
+					return errno
+				})
+				if err != nil {
+					log.Printf("ERROR: grab input device %s: %v", path, err)
+				}
+			}
+			log.Println("Opened input device", path)
+			open[path] = true
+			go readEvents(ctx, f, path, in.absRanges(f), events, lost)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-scan.C:
+		case path := <-lost:
+			delete(open, path)
+			held = make(map[int]int)
+			stopped, err := in.mpg.stop(ctx, in)
+			if err != nil {
+				log.Printf("ERROR: input device %s lost, stop jog: %v", path, err)
+			} else if stopped {
+				log.Printf("WARN: input device %s lost, stopped jog", path)
+			}
+		case <-t.C:
+			// repeat held jogs each slice, the MPG engine cancels the jog once they are released
+			var steps []pendantStep
+			for i, dir := range held {
+				b := in.bind.Bindings[i]
+				axis, ok := b.jogAxis()
+				if !ok {
+					continue
+				}
+				steps = append(steps, pendantStep{Axis: axis, Detents: dir, Size: b.Step})
+			}
+			if len(steps) > 0 {
+				in.mpg.add(in, steps)
+			}
+		case e := <-events:
+			in.handle(ctx, e, held)
+		}
+	}
+}
+
+// ioctl will call fn with the file descriptor, without switching the file to blocking mode like Fd does.
+func ioctl(f *os.File, fn func(fd uintptr) syscall.Errno) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var errno syscall.Errno
+	err = raw.Control(func(fd uintptr) { errno = fn(fd) })
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// absRanges returns the range of each bound absolute axis of the device. Axes that can't be queried
+// are left out, and treated as centred on 0.
+func (in *EvdevInput) absRanges(f *os.File) map[uint16]absRange {
+	ranges := make(map[uint16]absRange)
+	for _, b := range in.bind.Bindings {
+		if b.evType != evAbs {
+			continue
+		}
+		if _, ok := ranges[b.Code]; ok {
+			continue
+		}
+
+		// value, minimum, maximum, fuzz, flat, resolution
+		var info [6]int32
+		err := ioctl(f, func(fd uintptr) syscall.Errno {
+			_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, evioCGAbs+uintptr(b.Code), uintptr(unsafe.Pointer(&info)))
+			return errno
+		})
+		if err != nil {
+			// not every matching device has every bound axis
+			continue
+		}
+		ranges[b.Code] = absRange{Min: info[1], Max: info[2], Flat: info[4]}
+	}
+	return ranges
+}
+
+// missing returns device paths that match the bindings but are not open yet.
+func (in *EvdevInput) missing(open map[string]bool) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, pattern := range in.bind.Devices {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Printf("ERROR: input device pattern '%s': %v", pattern, err)
+			continue
+		}
+		for _, m := range matches {
+			// by-id and by-path names are links to the same event device
+			path, err := filepath.EvalSymlinks(m)
+			if err != nil || open[path] || seen[path] {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func (in *EvdevInput) handle(ctx context.Context, e evdevEvent, held map[int]int) {
+	for i, b := range in.bind.Bindings {
+		if b.evType != e.Type || b.Code != e.Code {
+			continue
+		}
+
+		axis, isJog := b.jogAxis()
+		switch e.Type {
+		case evKey:
+			switch {
+			case e.Value == 2:
+				// autorepeat, held keys are repeated by Run
+			case isJog && e.Value == 1:
+				held[i] = 1
+				in.mpg.add(in, []pendantStep{{Axis: axis, Detents: 1, Size: b.Step}})
+			case isJog:
+				delete(held, i)
+			case e.Value == 1:
+				in.run(ctx, b.Action)
+			}
+		case evAbs:
+			dir := e.abs.direction(e.Value)
+			if b.Value < 0 && dir > 0 || b.Value > 0 && dir < 0 {
+				// pushed the other way
+				dir = 0
+			}
+			prev, wasActive := held[i]
+			switch {
+			case dir == 0:
+				delete(held, i)
+			case isJog && (!wasActive || prev != dir):
+				held[i] = dir
+				in.mpg.add(in, []pendantStep{{Axis: axis, Detents: dir, Size: b.Step}})
+			case !isJog && !wasActive:
+				// track non-jog actions as held too, so they only run once per press
+				held[i] = 0
+				in.run(ctx, b.Action)
+			}
+		case evRel:
+			if isJog {
+				in.mpg.add(in, []pendantStep{{Axis: axis, Detents: int(e.Value), Size: b.Step}})
+			} else if e.Value != 0 {
+				in.run(ctx, b.Action)
+			}
+		}
+	}
+}
+
+// run will start the action without waiting, so actions that wait for the controller don't stall
+// reading events or stopping jogs.
+func (in *EvdevInput) run(ctx context.Context, action string) {
+	go func() {
+		err := runPendantAction(ctx, in.ctrl, action)
+		if err != nil {
+			log.Printf("ERROR: input action %s: %v", action, err)
+		}
+	}()
+}
+
+// readEvents will send events from the device until it fails or the context is canceled.
+func readEvents(ctx context.Context, f *os.File, path string, ranges map[uint16]absRange, events chan<- evdevEvent, lost chan<- string) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// unblock the read when canceled
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		f.Close()
+	}()
+
+	var e inputEvent
+	buf := make([]byte, unsafe.Sizeof(e))
+	for {
+		_, err := io.ReadFull(f, buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, os.ErrClosed) {
+				log.Printf("ERROR: read input device %s: %v", path, err)
+			}
+			select {
+			case lost <- path:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		e = *(*inputEvent)(unsafe.Pointer(&buf[0]))
+		if e.Type != evKey && e.Type != evRel && e.Type != evAbs {
+			continue
+		}
+		ev := evdevEvent{inputEvent: e}
+		if e.Type == evAbs {
+			ev.abs = ranges[e.Code]
+		}
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+//go:build linux
+// +build linux
+
+package spjs
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEvdevInputHandle(t *testing.T) {
+	b, err := LoadEvdevBindings(strings.NewReader(`{
+		"devices": ["/dev/null"],
+		"bindings": [
+			{"type": "key", "code": 103, "action": "jog:Y", "step": 1},
+			{"type": "abs", "code": 0, "action": "jog:X", "step": 0.5},
+			{"type": "abs", "code": 17, "value": -1, "action": "jog:Z", "step": 2},
+			{"type": "rel", "code": 8, "action": "jog:A", "step": 0.25}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := &EvdevInput{mpg: newMPGJogger(nil), bind: b}
+	held := make(map[int]int)
+	ctx := context.Background()
+	send := func(typ, code uint16, value int32, abs absRange) {
+		in.handle(ctx, evdevEvent{inputEvent: inputEvent{Type: typ, Code: code, Value: value}, abs: abs}, held)
+	}
+	pending := func(axis rune, want float64) {
+		t.Helper()
+		in.mpg.mx.Lock()
+		got := in.mpg.pending[axis]
+		in.mpg.pending = make(map[rune]float64)
+		in.mpg.mx.Unlock()
+		if got != want {
+			t.Errorf("pending %c = %g; want %g", axis, got, want)
+		}
+	}
+	isHeld := func(i, want int, wantOK bool) {
+		t.Helper()
+		got, ok := held[i]
+		if ok != wantOK || got != want {
+			t.Errorf("held[%d] = %d, %t; want %d, %t", i, got, ok, want, wantOK)
+		}
+	}
+
+	// key press, autorepeat, release
+	send(evKey, 103, 1, absRange{})
+	pending('Y', 1)
+	isHeld(0, 1, true)
+	send(evKey, 103, 2, absRange{})
+	pending('Y', 0)
+	send(evKey, 103, 0, absRange{})
+	isHeld(0, 0, false)
+
+	// joystick centred on 127, jogs in the direction it is pushed
+	stick := absRange{Min: 0, Max: 255}
+	send(evAbs, 0, 130, stick)
+	pending('X', 0)
+	isHeld(1, 0, false)
+	send(evAbs, 0, 0, stick)
+	pending('X', -0.5)
+	isHeld(1, -1, true)
+	send(evAbs, 0, 10, stick)
+	pending('X', 0)
+	send(evAbs, 0, 255, stick)
+	pending('X', 0.5)
+	isHeld(1, 1, true)
+	send(evAbs, 0, 127, stick)
+	isHeld(1, 0, false)
+
+	// d-pad bound to one direction only
+	hat := absRange{Min: -1, Max: 1}
+	send(evAbs, 17, 1, hat)
+	pending('Z', 0)
+	isHeld(2, 0, false)
+	send(evAbs, 17, -1, hat)
+	pending('Z', -2)
+	isHeld(2, -1, true)
+	send(evAbs, 17, 0, hat)
+	isHeld(2, 0, false)
+
+	// jog wheel
+	send(evRel, 8, -3, absRange{})
+	pending('A', -0.75)
+
+	// unbound code
+	send(evKey, 1, 1, absRange{})
+	if len(held) != 0 {
+		t.Errorf("held = %v; want empty", held)
+	}
+}
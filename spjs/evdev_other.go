@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package spjs
+
+import (
+	"context"
+	"errors"
+)
+
+// Run always returns an error, input devices are only supported on Linux.
+func (in *EvdevInput) Run(ctx context.Context) error {
+	return errors.New("input devices are only supported on Linux")
+}
@@ -0,0 +1,83 @@
+package spjs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadEvdevBindings(t *testing.T) {
+	b, err := LoadEvdevBindings(strings.NewReader(`{
+		"devices": ["/dev/input/by-id/*-event-joystick"],
+		"grab": true,
+		"bindings": [
+			{"type": "key", "code": 304, "action": "feedhold"},
+			{"type": "abs", "code": 16, "value": -1, "action": "jog:X", "step": 0.1},
+			{"type": "rel", "code": 8, "action": "jog:Z", "step": 0.01}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Grab || len(b.Devices) != 1 || len(b.Bindings) != 3 {
+		t.Fatalf("got %+v", b)
+	}
+	for i, want := range []uint16{evKey, evAbs, evRel} {
+		if b.Bindings[i].evType != want {
+			t.Errorf("binding %d: evType = %d; want %d", i, b.Bindings[i].evType, want)
+		}
+	}
+	if axis, ok := b.Bindings[1].jogAxis(); !ok || axis != 'X' {
+		t.Errorf("binding 1: jogAxis = %c, %t; want X, true", axis, ok)
+	}
+	if _, ok := b.Bindings[0].jogAxis(); ok {
+		t.Error("binding 0: jogAxis ok for a non-jog action")
+	}
+
+	for _, bad := range []string{
+		`{"devices": [], "bindings": []}`,
+		`{"devices": ["a"], "bindings": [{"type": "foo", "code": 1, "action": "feedhold"}]}`,
+		`{"devices": ["a"], "bindings": [{"type": "key", "code": 1, "action": "jog:Q", "step": 1}]}`,
+		`{"devices": ["a"], "bindings": [{"type": "key", "code": 1, "action": "jog:X"}]}`,
+		`{"devices": ["a"], "bindings": [{"type": "key", "code": 1, "action": "explode"}]}`,
+		`{"devices": ["a"]`,
+	} {
+		_, err := LoadEvdevBindings(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestAbsRangeDirection(t *testing.T) {
+	check := func(r absRange, v int32, want int) {
+		t.Helper()
+		if got := r.direction(v); got != want {
+			t.Errorf("%+v direction(%d) = %d; want %d", r, v, got, want)
+		}
+	}
+
+	// d-pad hat
+	hat := absRange{Min: -1, Max: 1}
+	check(hat, -1, -1)
+	check(hat, 0, 0)
+	check(hat, 1, 1)
+
+	// joystick resting off-centre
+	stick := absRange{Min: 0, Max: 255}
+	check(stick, 0, -1)
+	check(stick, 127, 0)
+	check(stick, 128, 0)
+	check(stick, 150, 0)
+	check(stick, 255, 1)
+
+	// reported dead zone
+	flat := absRange{Min: -32768, Max: 32767, Flat: 4000}
+	check(flat, 3000, 0)
+	check(flat, -5000, -1)
+	check(flat, 5000, 1)
+
+	// unknown range
+	check(absRange{}, -3, -1)
+	check(absRange{}, 0, 0)
+	check(absRange{}, 3, 1)
+}
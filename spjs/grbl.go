@@ -3,12 +3,20 @@ package spjs
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
 )
 
 type GRBL struct {
 	port *Port
 
+	mx          sync.Mutex
+	reportUnits Units
+	modalUnits  Units
+
+	*statusPoller
+
 	firstStatus bool
 	statCh      chan GRBLStatus
 	statExtCh   chan ControllerStatus
@@ -18,15 +26,41 @@ var _ Driver = &GRBL{}
 
 func NewGRBL() *GRBL {
 	return &GRBL{
-		statCh:    make(chan GRBLStatus, 1),
-		statExtCh: make(chan ControllerStatus),
+		statCh:       make(chan GRBLStatus, 1),
+		statExtCh:    make(chan ControllerStatus),
+		statusPoller: newStatusPoller("GRBL", "?"),
 	}
 }
 
 func (g *GRBL) WrapGCode(data []string) string { return strings.Join(data, "\n") + "\n" }
 
-// SetPort will set the control port.
-func (g *GRBL) SetPort(p *Port) { g.port = p }
+// SetPort will set the control port. Settings and work offsets are re-queried each time the port is opened.
+//
+// Status reports are polled for as long as the client is running.
+func (g *GRBL) SetPort(p *Port) {
+	g.port = p
+	go func() {
+		for e := range p.Events() {
+			if e.Type != PortOpened {
+				continue
+			}
+			g.statusPoller.reset()
+			g.queryState(context.Background())
+		}
+	}()
+	go g.statusPoller.run(p.cli.ctx, p)
+}
+
+// queryState requests settings (`$$`) and work coordinate offsets (`$#`) needed to interpret status reports.
+func (g *GRBL) queryState(ctx context.Context) {
+	for _, cmd := range []string{"$$\n", "$#\n"} {
+		err := g.port.SendCommand(ctx, cmd, false)
+		if err != nil {
+			log.Printf("ERROR: query GRBL state (%s): %v", strings.TrimSpace(cmd), err)
+			return
+		}
+	}
+}
 
 // Name will always return the string `GRBL`.
 func (g *GRBL) Name() string { return "GRBL" }
@@ -42,10 +76,73 @@ func (g *GRBL) CycleStart() string { return "~" }
 func (g *GRBL) Home() string       { return "$H\n" }
 func (g *GRBL) EStop() string      { return "\x18" }
 func (g *GRBL) Reset() string      { return "\x18" }
-func (g *GRBL) Jog(axis rune, mm float64) string {
-	return fmt.Sprintf("$J=G21G91F10000%c%0.4g\n", axis, mm)
+func (g *GRBL) SpindleOff() string { return "M5\n" }
+
+// ToggleFlood uses the realtime coolant toggle (0xA0) so it can be used while a job is running.
+//
+// SPJS writes the command UTF-8 encoded, GRBL discards the unassigned 0xC2 lead byte.
+func (g *GRBL) ToggleFlood() string { return string(rune(0xA0)) }
+
+// ToggleMist uses the realtime coolant toggle (0xA1) so it can be used while a job is running.
+func (g *GRBL) ToggleMist() string { return string(rune(0xA1)) }
+
+// JogCancel uses the realtime jog cancel (0x85), which also discards any jog motions still in the planner.
+//
+// SPJS writes the command UTF-8 encoded, GRBL discards the unassigned 0xC2 lead byte.
+func (g *GRBL) JogCancel() string { return string(rune(0x85)) }
+
+func (g *GRBL) SpindleOn(rpm float64, ccw bool) string {
+	if ccw {
+		return fmt.Sprintf("M4S%.f\n", rpm)
+	}
+	return fmt.Sprintf("M3S%.f\n", rpm)
+}
+func (g *GRBL) Jog(moves ...AxisMove) string { return g.JogFeed(10000, moves...) }
+
+// JogFeed uses `$J=`, the units and distance mode only apply to the jog and leave the modal state unchanged.
+func (g *GRBL) JogFeed(feed float64, moves ...AxisMove) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "$J=G21G91F%.f", feed)
+	for _, m := range moves {
+		buf.WriteString(Millimeters.axisWord(m.Axis, m.MM))
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// WPos is sent in the current modal units, as switching to G21 would also change them for the commands that follow.
+//
+// The modal units are only known from the last parser state report (`$G`), see ParserStateQuery.
+func (g *GRBL) WPos(axis rune, mm float64) string {
+	g.mx.Lock()
+	units := g.modalUnits
+	g.mx.Unlock()
+	return "G10L20P1" + units.axisWord(axis, mm) + "\n"
+}
+
+// ParserStateQuery uses `$G`, so the modal units are known before WPos is called.
+func (g *GRBL) ParserStateQuery() string { return "$G\n" }
+
+// setParserState will record the modal units from a parser state report (e.g. `G0 G54 G17 G21 G90 G94 M5 M9 T0 F0 S0`).
+func (g *GRBL) setParserState(s string) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	for _, word := range strings.Fields(s) {
+		switch word {
+		case "G20":
+			g.modalUnits = Inches
+		case "G21":
+			g.modalUnits = Millimeters
+		}
+	}
+}
+
+// ReportUnits returns the units GRBL is configured to report in (`$13`).
+func (g *GRBL) ReportUnits() Units {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	return g.reportUnits
 }
-func (g *GRBL) WPos(axis rune, mm float64) string { return fmt.Sprintf("G10L20P1%c%0.4g\n?", axis, mm) }
 
 // LastStatus will return the last available status. It will block until the first status message is processed.
 func (g *GRBL) LastStatus() ControllerStatus {
@@ -54,11 +151,59 @@ func (g *GRBL) LastStatus() ControllerStatus {
 	return stat
 }
 
+// CurrentStatus returns the last status without waiting for the first status message.
+func (g *GRBL) CurrentStatus() (ControllerStatus, bool) {
+	select {
+	case stat := <-g.statCh:
+		g.statCh <- stat
+		return stat, true
+	default:
+		return nil, false
+	}
+}
+
+// BufferFree returns the free planner blocks and RX bytes from the last status report, if `Bf:` is enabled (`$10`).
+func (g *GRBL) BufferFree() (planner, rx int, ok bool) {
+	select {
+	case stat := <-g.statCh:
+		g.statCh <- stat
+		return stat.Buffer.Planner, stat.Buffer.RX, stat.Buffer.Valid
+	default:
+		return 0, 0, false
+	}
+}
+
 // Status will return a channel that will get updates each time status data is updated. It always returns the same channel.
 func (g *GRBL) Status() <-chan ControllerStatus { return g.statExtCh }
 
 // HandleData will process data coming from GRBL. It is only intended to be used by the SPJS client code.
 func (g *GRBL) HandleData(ctx context.Context, data string) error {
+	if strings.HasPrefix(data, "$13=") {
+		var inches int
+		_, err := fmt.Sscanf(strings.TrimSpace(data), "$13=%d", &inches)
+		if err != nil {
+			return err
+		}
+		g.mx.Lock()
+		g.reportUnits = Millimeters
+		if inches == 1 {
+			g.reportUnits = Inches
+		}
+		g.mx.Unlock()
+		return nil
+	}
+
+	if line := strings.TrimSpace(data); strings.HasPrefix(line, "[GC:") {
+		g.setParserState(strings.TrimSuffix(line[4:], "]"))
+		return nil
+	}
+
+	// GRBL prints a welcome message after every reset, settings may have changed
+	if strings.HasPrefix(strings.TrimSpace(data), "Grbl ") && g.port != nil {
+		go g.queryState(ctx)
+		return nil
+	}
+
 	if !strings.HasPrefix(data, "<") {
 		return nil
 	}
@@ -69,6 +214,7 @@ func (g *GRBL) HandleData(ctx context.Context, data string) error {
 	}
 
 	newStat := stat
+	newStat.ReportUnits = g.ReportUnits()
 	err := newStat.Parse(data)
 	if err != nil {
 		if g.firstStatus {
@@ -80,6 +226,13 @@ func (g *GRBL) HandleData(ctx context.Context, data string) error {
 	g.firstStatus = true
 	g.statCh <- newStat
 
+	switch newStat.State {
+	case GRBLStateIdle, GRBLStateAlarm, GRBLStateDoor, GRBLStateCheck, GRBLStateSleep, GRBLStateTool:
+		g.statusPoller.reported(false)
+	default:
+		g.statusPoller.reported(true)
+	}
+
 	select {
 	case g.statExtCh <- newStat:
 	default:
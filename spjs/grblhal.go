@@ -0,0 +1,203 @@
+package spjs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GRBLHAL is a driver for grblHAL. It supports everything GRBL does, along with extended info (`$I`),
+// tool tables and extra status fields.
+type GRBLHAL struct {
+	*GRBL
+
+	halMx sync.Mutex
+	info  GRBLHALInfo
+	tools map[int]GRBLTool
+}
+
+// GRBLHALInfo is the build info reported by `$I`.
+type GRBLHALInfo struct {
+	Version  string
+	Firmware string
+	Driver   string
+	Board    string
+
+	// Options is the original `OPT:` string, Extensions are the `NEWOPT:` values (e.g. `TC`, `SD`).
+	Options    string
+	Extensions []string
+
+	// AxisNames are the configured axes in order (e.g. `XYZABC`).
+	AxisNames string
+}
+
+// HasExtension returns true if grblHAL reported the extension (e.g. `TC` or `SD`) in `NEWOPT:`.
+func (info GRBLHALInfo) HasExtension(name string) bool {
+	for _, ext := range info.Extensions {
+		if ext == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GRBLTool is an entry in the grblHAL tool table.
+type GRBLTool struct {
+	Number int
+	Offset Position
+	Radius float64
+}
+
+var _ Driver = &GRBLHAL{}
+
+func NewGRBLHAL() *GRBLHAL {
+	return &GRBLHAL{GRBL: NewGRBL()}
+}
+
+// Name will always return the string `grblHAL`.
+func (g *GRBLHAL) Name() string { return "grblHAL" }
+
+// SetPort will set the control port. Build info is re-queried each time the port is opened.
+func (g *GRBLHAL) SetPort(p *Port) {
+	g.GRBL.SetPort(p)
+	go func() {
+		for e := range p.Events() {
+			if e.Type != PortOpened {
+				continue
+			}
+			g.queryInfo(context.Background())
+		}
+	}()
+}
+
+func (g *GRBLHAL) queryInfo(ctx context.Context) {
+	err := g.port.SendCommand(ctx, "$I\n", false)
+	if err != nil {
+		log.Println("ERROR: query grblHAL info:", err)
+	}
+}
+
+// Detected returns true once `$I` has confirmed the firmware is grblHAL.
+func (g *GRBLHAL) Detected() bool {
+	g.halMx.Lock()
+	defer g.halMx.Unlock()
+	return strings.EqualFold(g.info.Firmware, "grblHAL")
+}
+
+// Info returns the build info from the last `$I` response.
+func (g *GRBLHAL) Info() GRBLHALInfo {
+	g.halMx.Lock()
+	defer g.halMx.Unlock()
+	info := g.info
+	info.Extensions = append([]string(nil), info.Extensions...)
+	return info
+}
+
+// Tools returns the tool table from the last `$#` response. Offsets are in mm.
+func (g *GRBLHAL) Tools() []GRBLTool {
+	g.halMx.Lock()
+	defer g.halMx.Unlock()
+
+	tools := make([]GRBLTool, 0, len(g.tools))
+	for _, t := range g.tools {
+		tools = append(tools, t)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Number < tools[j].Number })
+	return tools
+}
+
+// HandleData will process data coming from grblHAL. It is only intended to be used by the SPJS client code.
+func (g *GRBLHAL) HandleData(ctx context.Context, data string) error {
+	line := strings.TrimSpace(data)
+	switch {
+	case strings.HasPrefix(line, "GrblHAL ") && g.port != nil:
+		// printed after every reset, settings and tools may have changed
+		go func() {
+			g.queryState(ctx)
+			g.queryInfo(ctx)
+		}()
+		return nil
+	case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+		return g.handleInfo(line[1 : len(line)-1])
+	}
+
+	return g.GRBL.HandleData(ctx, data)
+}
+
+func (g *GRBLHAL) handleInfo(s string) error {
+	p := strings.SplitN(s, ":", 2)
+	if len(p) != 2 {
+		return nil
+	}
+
+	g.halMx.Lock()
+	defer g.halMx.Unlock()
+
+	switch p[0] {
+	case "VER":
+		// the version is followed by the optional build name (e.g. `1.1f.20210607:my machine`)
+		g.info.Version = strings.SplitN(p[1], ":", 2)[0]
+	case "OPT":
+		g.info.Options = p[1]
+	case "NEWOPT":
+		g.info.Extensions = strings.Split(p[1], ",")
+	case "FIRMWARE":
+		g.info.Firmware = p[1]
+	case "DRIVER":
+		g.info.Driver = p[1]
+	case "BOARD":
+		g.info.Board = p[1]
+	case "GC":
+		g.setParserState(p[1])
+	case "AXS":
+		// e.g. `6:XYZABC`
+		axs := strings.SplitN(p[1], ":", 2)
+		g.info.AxisNames = axs[len(axs)-1]
+	case "T":
+		t, err := g.parseTool(p[1])
+		if err != nil {
+			return fmt.Errorf("parse tool '%s': %w", p[1], err)
+		}
+		if g.tools == nil {
+			g.tools = make(map[int]GRBLTool)
+		}
+		g.tools[t.Number] = t
+	}
+
+	return nil
+}
+
+// parseTool will parse a tool table entry from `$#` (e.g. `1|0.000,0.000,-10.000|1.500`).
+func (g *GRBLHAL) parseTool(s string) (GRBLTool, error) {
+	parts := strings.Split(s, "|")
+	if len(parts) < 2 {
+		return GRBLTool{}, fmt.Errorf("missing offset")
+	}
+
+	var t GRBLTool
+	var err error
+	t.Number, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return GRBLTool{}, err
+	}
+	_, err = t.Offset.parse(parts[1])
+	if err != nil {
+		return GRBLTool{}, err
+	}
+
+	units := g.ReportUnits()
+	t.Offset = units.PositionToMM(t.Offset)
+	if len(parts) > 2 {
+		t.Radius, err = strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return GRBLTool{}, err
+		}
+		t.Radius = units.ToMM(t.Radius)
+	}
+
+	return t, nil
+}
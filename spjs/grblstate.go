@@ -0,0 +1,71 @@
+package spjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GRBLState is the machine state from a GRBL status report.
+type GRBLState int
+
+const (
+	GRBLStateUnknown GRBLState = iota
+	GRBLStateIdle
+	GRBLStateRun
+	GRBLStateHold
+	GRBLStateJog
+	GRBLStateAlarm
+	GRBLStateDoor
+	GRBLStateCheck
+	GRBLStateHome
+	GRBLStateSleep
+
+	// GRBLStateTool is reported by grblHAL while waiting for a manual tool change.
+	GRBLStateTool
+)
+
+var grblStateNames = []string{
+	GRBLStateUnknown: "Unknown",
+	GRBLStateIdle:    "Idle",
+	GRBLStateRun:     "Run",
+	GRBLStateHold:    "Hold",
+	GRBLStateJog:     "Jog",
+	GRBLStateAlarm:   "Alarm",
+	GRBLStateDoor:    "Door",
+	GRBLStateCheck:   "Check",
+	GRBLStateHome:    "Home",
+	GRBLStateSleep:   "Sleep",
+	GRBLStateTool:    "Tool",
+}
+
+func (s GRBLState) String() string {
+	if s < 0 || int(s) >= len(grblStateNames) {
+		return fmt.Sprintf("GRBLState(%d)", int(s))
+	}
+	return grblStateNames[s]
+}
+
+// parseGRBLState will parse a state like `Hold:1`, returning -1 for the substate if there isn't one.
+//
+// Unrecognized states (e.g. from newer firmware) are returned as GRBLStateUnknown rather than an error,
+// so the rest of the status report is still used.
+func parseGRBLState(s string) (GRBLState, int, error) {
+	name := s
+	sub := -1
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		name = s[:i]
+		_, err := fmt.Sscanf(s[i+1:], "%d", &sub)
+		if err != nil {
+			return GRBLStateUnknown, -1, fmt.Errorf("parse substate '%s': %w", s, err)
+		}
+	}
+
+	for i, n := range grblStateNames {
+		// GRBL 0.9 used upper-case names for some states (e.g. `ALARM`)
+		if i > 0 && strings.EqualFold(n, name) {
+			return GRBLState(i), sub, nil
+		}
+	}
+
+	return GRBLStateUnknown, sub, nil
+}
@@ -0,0 +1,38 @@
+package spjs
+
+import "testing"
+
+func TestParseGRBLState(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		state GRBLState
+		sub   int
+		err   bool
+	}{
+		{s: "Idle", state: GRBLStateIdle, sub: -1},
+		{s: "Run", state: GRBLStateRun, sub: -1},
+		{s: "Hold:0", state: GRBLStateHold, sub: 0},
+		{s: "Hold:1", state: GRBLStateHold, sub: 1},
+		{s: "Door:2", state: GRBLStateDoor, sub: 2},
+		{s: "Jog", state: GRBLStateJog, sub: -1},
+		{s: "Tool", state: GRBLStateTool, sub: -1},
+		{s: "ALARM", state: GRBLStateAlarm, sub: -1},
+		{s: "Queue", state: GRBLStateUnknown, sub: -1},
+		{s: "Hold:x", err: true},
+	} {
+		state, sub, err := parseGRBLState(c.s)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected error", c.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.s, err)
+			continue
+		}
+		if state != c.state || sub != c.sub {
+			t.Errorf("%s: got %s, %d; want %s, %d", c.s, state, sub, c.state, c.sub)
+		}
+	}
+}
@@ -2,24 +2,60 @@ package spjs
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
 type GRBLStatus struct {
-	Status          string
+	Status string
+	State  GRBLState
+
+	// SubState is the number after the state (e.g. `Hold:1` or `Door:2`), or -1 if there isn't one.
+	SubState int
+
+	// Line is the line number being executed (`Ln:`), or 0 if not reported.
+	Line int
+
 	MPos, WPos, WCO Position
 
+	// Axes is the number of axes included in position reports.
+	Axes int
+
+	// ReportUnits are the units GRBL reports positions and rates in (`$13`). Parsed values are always stored in mm.
+	ReportUnits Units
+
 	Feed     float64
 	Spindle  float64
+	Buffer   GRBLBufferStatus
 	Pins     GRBLPinStatus
 	Override struct {
 		Feed, Rapid, Spindle float64
 	}
 	Accesory GRBLACCStatus
+
+	// Tool, MPG, Homed and SD are only reported by grblHAL.
+	Tool  int
+	MPG   bool
+	Homed bool
+	SD    GRBLSDStatus
 }
 
 var _ ControllerStatus = GRBLStatus{}
 
+// GRBLBufferStatus is the free space reported by `Bf:`, Valid is false if it has not been reported.
+type GRBLBufferStatus struct {
+	Valid   bool
+	Planner int
+	RX      int
+}
+
+// GRBLSDStatus is the progress of a job running from the SD card (`SD:`), Valid is false if none is running.
+type GRBLSDStatus struct {
+	Valid   bool
+	Percent float64
+	File    string
+}
+
 type GRBLPinStatus struct{ X, Y, Z, P, D, H, R, S bool }
 type GRBLACCStatus struct {
 	SpindleEnabled bool
@@ -28,11 +64,31 @@ type GRBLACCStatus struct {
 	Mist           bool
 }
 
-func (stat GRBLStatus) IsAlarm() bool             { return strings.HasPrefix(stat.Status, "ALARM") }
-func (stat GRBLStatus) IsReady() bool             { return stat.Status == "Idle" }
+func (stat GRBLStatus) IsAlarm() bool             { return stat.State == GRBLStateAlarm }
+func (stat GRBLStatus) IsReady() bool             { return stat.State == GRBLStateIdle }
 func (stat GRBLStatus) MachinePosition() Position { return stat.MPos }
 func (stat GRBLStatus) WorkPosition() Position    { return stat.WPos }
 func (stat GRBLStatus) StatusText() string        { return stat.Status }
+func (stat GRBLStatus) FeedRate() float64         { return stat.Feed }
+func (stat GRBLStatus) Accessories() AccessoryStatus {
+	return AccessoryStatus{
+		SpindleEnabled: stat.Accesory.SpindleEnabled,
+		SpindleCCW:     stat.Accesory.SpindleCCW,
+		SpindleSpeed:   stat.Spindle,
+		Flood:          stat.Accesory.Flood,
+		Mist:           stat.Accesory.Mist,
+	}
+}
+
+// HoldComplete returns true if a feed hold has finished decelerating (`Hold:0`) and it is safe to resume or reset.
+func (stat GRBLStatus) HoldComplete() bool { return stat.State == GRBLStateHold && stat.SubState == 0 }
+
+func (stat GRBLStatus) AxisCount() int {
+	if stat.Axes == 0 {
+		return 3
+	}
+	return stat.Axes
+}
 
 func (stat *GRBLStatus) Parse(data string) error {
 
@@ -42,7 +98,15 @@ func (stat *GRBLStatus) Parse(data string) error {
 	parts := strings.Split(data, "|")
 	stat.Status = parts[0]
 	stat.Pins = GRBLPinStatus{}
-	var useMPos bool
+	stat.Line = 0
+	stat.SD = GRBLSDStatus{}
+	var useMPos, hasOv, hasA bool
+
+	var err error
+	stat.State, stat.SubState, err = parseGRBLState(parts[0])
+	if err != nil {
+		return err
+	}
 
 	for _, part := range parts[1:] {
 		p := strings.SplitN(part, ":", 2)
@@ -51,35 +115,53 @@ func (stat *GRBLStatus) Parse(data string) error {
 		switch p[0] {
 		case "MPos":
 			useMPos = true
-			_, err = fmt.Sscanf(p[1], "%f,%f,%f", &stat.MPos.X, &stat.MPos.Y, &stat.MPos.Z)
-			stat.WPos.X = stat.MPos.X - stat.WCO.X
-			stat.WPos.Y = stat.MPos.Y - stat.WCO.Y
-			stat.WPos.Z = stat.MPos.Z - stat.WCO.Z
+			stat.Axes, err = stat.MPos.parse(p[1])
+			stat.MPos = stat.ReportUnits.PositionToMM(stat.MPos)
+			stat.WPos = stat.MPos.Sub(stat.WCO)
 		case "WPos":
-			_, err = fmt.Sscanf(p[1], "%f,%f,%f", &stat.WPos.X, &stat.WPos.Y, &stat.WPos.Z)
-			stat.MPos.X = stat.WPos.X + stat.WCO.X
-			stat.MPos.Y = stat.WPos.Y + stat.WCO.Y
-			stat.MPos.Z = stat.WPos.Z + stat.WCO.Z
+			stat.Axes, err = stat.WPos.parse(p[1])
+			stat.WPos = stat.ReportUnits.PositionToMM(stat.WPos)
+			stat.MPos = stat.WPos.Add(stat.WCO)
 		case "WCO":
-			_, err = fmt.Sscanf(p[1], "%f,%f,%f", &stat.WCO.X, &stat.WCO.Y, &stat.WCO.Z)
+			_, err = stat.WCO.parse(p[1])
+			stat.WCO = stat.ReportUnits.PositionToMM(stat.WCO)
 			if useMPos {
-				stat.WPos.X = stat.MPos.X - stat.WCO.X
-				stat.WPos.Y = stat.MPos.Y - stat.WCO.Y
-				stat.WPos.Z = stat.MPos.Z - stat.WCO.Z
+				stat.WPos = stat.MPos.Sub(stat.WCO)
 			} else {
-				stat.MPos.X = stat.WPos.X + stat.WCO.X
-				stat.MPos.Y = stat.WPos.Y + stat.WCO.Y
-				stat.MPos.Z = stat.WPos.Z + stat.WCO.Z
+				stat.MPos = stat.WPos.Add(stat.WCO)
 			}
 		case "F":
 			_, err = fmt.Sscanf(p[1], "%f", &stat.Feed)
+			stat.Feed = stat.ReportUnits.ToMM(stat.Feed)
 		case "FS":
 			_, err = fmt.Sscanf(p[1], "%f,%f", &stat.Feed, &stat.Spindle)
+			stat.Feed = stat.ReportUnits.ToMM(stat.Feed)
+		case "Ln":
+			_, err = fmt.Sscanf(p[1], "%d", &stat.Line)
+		case "Bf":
+			stat.Buffer.Valid = true
+			_, err = fmt.Sscanf(p[1], "%d,%d", &stat.Buffer.Planner, &stat.Buffer.RX)
+		case "T":
+			stat.Tool, err = strconv.Atoi(p[1])
+		case "MPG":
+			stat.MPG = p[1] == "1"
+		case "H":
+			// homed state, optionally followed by the homed axes mask
+			stat.Homed = strings.HasPrefix(p[1], "1")
+		case "SD":
+			sd := strings.SplitN(p[1], ",", 2)
+			stat.SD = GRBLSDStatus{Valid: true}
+			stat.SD.Percent, err = strconv.ParseFloat(sd[0], 64)
+			if len(sd) == 2 {
+				stat.SD.File = sd[1]
+			}
 		case "Pn":
 			stat.Pins.parse(p[1])
 		case "Ov":
+			hasOv = true
 			_, err = fmt.Sscanf(p[1], "%f,%f,%f", &stat.Override.Feed, &stat.Override.Rapid, &stat.Override.Spindle)
 		case "A":
+			hasA = true
 			stat.Accesory.SpindleEnabled = strings.ContainsAny(p[1], "SC")
 			stat.Accesory.SpindleCCW = strings.ContainsRune(p[1], 'C')
 			stat.Accesory.Flood = strings.ContainsRune(p[1], 'F')
@@ -90,6 +172,11 @@ func (stat *GRBLStatus) Parse(data string) error {
 		}
 	}
 
+	// accessory state is only sent along with overrides, and omitted if everything is off
+	if hasOv && !hasA {
+		stat.Accesory = GRBLACCStatus{}
+	}
+
 	return nil
 }
 func (pins *GRBLPinStatus) parse(s string) {
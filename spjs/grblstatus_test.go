@@ -0,0 +1,83 @@
+package spjs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGRBLStatusParse(t *testing.T) {
+	for _, c := range []struct {
+		data  string
+		units Units
+		want  GRBLStatus
+	}{
+		{
+			data: "<Idle|MPos:1.000,2.000,3.000|FS:0,0|WCO:0.500,0.000,-1.000>",
+			want: GRBLStatus{
+				Status: "Idle", State: GRBLStateIdle, SubState: -1, Axes: 3,
+				MPos: Position{X: 1, Y: 2, Z: 3},
+				WPos: Position{X: 0.5, Y: 2, Z: 4},
+				WCO:  Position{X: 0.5, Z: -1},
+			},
+		},
+		{
+			data: "<Hold:0|WPos:1.000,2.000,3.000,4.000|Bf:15,128|Ln:42|FS:500,12000|Pn:XZP|Ov:100,90,80|A:SF>",
+			want: GRBLStatus{
+				Status: "Hold:0", State: GRBLStateHold, SubState: 0, Axes: 4, Line: 42,
+				MPos:     Position{X: 1, Y: 2, Z: 3, A: 4},
+				WPos:     Position{X: 1, Y: 2, Z: 3, A: 4},
+				Feed:     500,
+				Spindle:  12000,
+				Buffer:   GRBLBufferStatus{Valid: true, Planner: 15, RX: 128},
+				Pins:     GRBLPinStatus{X: true, Z: true, P: true},
+				Override: struct{ Feed, Rapid, Spindle float64 }{100, 90, 80},
+				Accesory: GRBLACCStatus{SpindleEnabled: true, Flood: true},
+			},
+		},
+		{
+			data:  "<Run|MPos:1.0000,0.5000,0.0000|F:10>",
+			units: Inches,
+			want: GRBLStatus{
+				Status: "Run", State: GRBLStateRun, SubState: -1, Axes: 3, ReportUnits: Inches,
+				MPos: Position{X: 25.4, Y: 12.7},
+				WPos: Position{X: 25.4, Y: 12.7},
+				Feed: 254,
+			},
+		},
+		{
+			// grblHAL
+			data: "<Tool|MPos:0.000,0.000,0.000|Bf:35,1023|FS:0,0|Pn:D|T:3|MPG:1|H:1,7|SD:45.2,/job.nc>",
+			want: GRBLStatus{
+				Status: "Tool", State: GRBLStateTool, SubState: -1, Axes: 3,
+				Buffer: GRBLBufferStatus{Valid: true, Planner: 35, RX: 1023},
+				Pins:   GRBLPinStatus{D: true},
+				Tool:   3,
+				MPG:    true,
+				Homed:  true,
+				SD:     GRBLSDStatus{Valid: true, Percent: 45.2, File: "/job.nc"},
+			},
+		},
+	} {
+		stat := GRBLStatus{ReportUnits: c.units}
+		err := stat.Parse(c.data)
+		if err != nil {
+			t.Errorf("%s: %v", c.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(stat, c.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", c.data, stat, c.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"<Hold:x|MPos:0.000,0.000,0.000>",
+		"<Idle|MPos:1.000,a,3.000>",
+		"<Idle|MPos:0,0,0,0,0,0,0>",
+		"<Idle|Bf:x,1>",
+	} {
+		var stat GRBLStatus
+		if err := stat.Parse(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
@@ -7,11 +7,12 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
 
-	// spjsJobLines is the min number of commands to keep in the SPJS queue at a time.
+	// spjsJobLines is the max number of job commands to keep in the SPJS queue at a time.
 	spjsJobLines = 500
 
 	// spjsLoadJobChunks is the max number of lines of a job to load at a time.
@@ -28,6 +29,17 @@ type jobController struct {
 
 	statusCh chan JobStatus
 
+	// unheldCh is closed while the job is allowed to send commands.
+	holdMx   sync.Mutex
+	unheldCh chan struct{}
+
+	// retry holds lines that never reached the controller, they are sent again before any new lines.
+	// inFlight is the number of sent lines that have not been resolved by the response loop.
+	retryMx  sync.Mutex
+	retry    []string
+	inFlight int
+	resolved chan struct{}
+
 	wg sync.WaitGroup
 }
 
@@ -36,7 +48,10 @@ func newJobController(ctx context.Context, ctrl *Controller, name string, r io.R
 		Controller: ctrl,
 		statusCh:   make(chan JobStatus, 1),
 		lines:      make(chan string, spjsJobLinesBuffer),
+		unheldCh:   make(chan struct{}),
+		resolved:   make(chan struct{}, 1),
 	}
+	close(jc.unheldCh)
 	jc.statusCh <- JobStatus{Valid: true, Name: name}
 	jc.ctx, jc.cancelFn = context.WithCancel(ctx)
 
@@ -51,6 +66,12 @@ func (jc *jobController) updateStatus(update func(s *JobStatus)) JobStatus {
 	stat := <-jc.statusCh
 	if stat.Err == nil {
 		update(&stat)
+
+		// only the latest status is kept for the reader
+		select {
+		case <-jc.Controller.jobStatus:
+		default:
+		}
 		select {
 		case jc.Controller.jobStatus <- stat:
 		default:
@@ -107,38 +128,86 @@ func (jc *jobController) Start() error {
 	}
 	jc.wg.Add(2)
 
-	ch := make(chan *commandCallback, spjsJobLines)
+	ch := make(chan sentLine, spjsJobLines)
 
 	// send commands
 	go func() {
 		defer jc.wg.Done()
 		defer close(ch)
 
-		var line string
-		var ok bool
+		// queue is the lines to send before reading more of the job, in order
+		var queue []string
+		var linesDone bool
 		for {
 			select {
-			case line, ok = <-jc.lines:
+			case <-jc.unheld():
 			case <-jc.ctx.Done():
 				return
 			}
-			if !ok {
-				// done sending
-				return
+
+			queue = append(jc.takeRetry(), queue...)
+			if len(queue) == 0 {
+				if linesDone {
+					if jc.linesInFlight() == 0 {
+						// done sending
+						return
+					}
+					// a line may still need to be sent again
+					select {
+					case <-jc.resolved:
+					case <-jc.ctx.Done():
+						return
+					}
+					continue
+				}
+
+				select {
+				case line, ok := <-jc.lines:
+					if !ok {
+						linesDone = true
+						continue
+					}
+					queue = append(queue, line)
+				case <-jc.resolved:
+					continue
+				case <-jc.ctx.Done():
+					return
+				}
+			}
+
+			// wait for SPJS to drain its queue, rather than only trusting our own count of outstanding commands
+			if jc.QueueCount() >= spjsJobLines {
+				select {
+				case <-jc.queueChanged():
+				case <-time.After(time.Second):
+				case <-jc.ctx.Done():
+					return
+				}
+				continue
 			}
 
-			cb, err := jc.sendCommand(jc.wrapGCode([]string{line}))
+			cb, err := jc.sendCommand(jc.wrapGCode([]string{queue[0]}))
+			if err != nil && (jc.cli.Check() != nil || !jc.Connected()) {
+				// retry the same line once the operator confirms
+				jc.hold("lost connection to SPJS")
+				continue
+			}
 			if err != nil {
 				jc.failWith(err)
 				// abort on failure
 				return
 			}
 
+			jc.retryMx.Lock()
+			jc.inFlight++
+			jc.retryMx.Unlock()
+
 			select {
 			case <-jc.ctx.Done():
 				return
-			case ch <- cb:
+			case ch <- sentLine{line: queue[0], cb: cb}:
 			}
+			queue = queue[1:]
 		}
 	}()
 
@@ -147,53 +216,171 @@ func (jc *jobController) Start() error {
 		defer jc.wg.Done()
 
 		for {
-			var callback *commandCallback
+			var sent sentLine
 			select {
-			case cb, ok := <-ch:
+			case next, ok := <-ch:
 				if !ok {
+					if jc.ctx.Err() == nil {
+						jc.updateStatus(func(s *JobStatus) { s.Done = true })
+					}
 					return
 				}
-				callback = cb
+				sent = next
 			case <-jc.ctx.Done():
 				return
 			}
 
+			var counted bool
 			select {
-			case <-callback.WriteCh:
+			case <-sent.cb.WriteCh:
 				jc.updateStatus(func(s *JobStatus) { s.Sent++ })
-			case <-callback.DoneCh:
-				if callback.Err != nil {
-					jc.failWith(callback.Err)
-					return
-				}
-				jc.updateStatus(func(s *JobStatus) {
-					s.Sent++
-					s.Completed++
-				})
-				continue
+				counted = true
+			case <-sent.cb.DoneCh:
 			case <-jc.ctx.Done():
 				return
 			}
 
 			select {
-			case <-callback.DoneCh:
-				if callback.Err != nil {
-					jc.failWith(callback.Err)
-					return
-				}
-				jc.updateStatus(func(s *JobStatus) {
-					s.Sent++
-					s.Completed++
-				})
+			case <-sent.cb.DoneCh:
 			case <-jc.ctx.Done():
 				return
 			}
+			if !jc.resolve(sent, counted) {
+				return
+			}
 		}
 	}()
 
 	return nil
 }
 
+// sentLine is a job line along with the callback for the command it was sent as.
+type sentLine struct {
+	line string
+	cb   *commandCallback
+}
+
+// resolve will record the result of a sent line. Lines that never reached the controller are queued to
+// be sent again, lines that were written but not acknowledged are left to the operator. It returns
+// false if the job failed.
+//
+// counted is true if the line was already counted as sent when it was written.
+func (jc *jobController) resolve(sent sentLine, counted bool) bool {
+	defer func() {
+		jc.retryMx.Lock()
+		jc.inFlight--
+		jc.retryMx.Unlock()
+
+		select {
+		case jc.resolved <- struct{}{}:
+		default:
+		}
+	}()
+
+	written := counted || sent.cb.wasWritten()
+	switch {
+	case jc.unconfirmed(sent.cb.Err) && !written:
+		jc.retryMx.Lock()
+		jc.retry = append(jc.retry, sent.line)
+		jc.retryMx.Unlock()
+		jc.updateStatus(func(s *JobStatus) { s.Requeued++ })
+	case jc.unconfirmed(sent.cb.Err):
+		jc.updateStatus(func(s *JobStatus) {
+			if !counted {
+				s.Sent++
+			}
+			s.Unconfirmed++
+		})
+	case sent.cb.Err != nil:
+		jc.failWith(sent.cb.Err)
+		return false
+	default:
+		jc.updateStatus(func(s *JobStatus) {
+			if !counted {
+				s.Sent++
+			}
+			s.Completed++
+		})
+	}
+	return true
+}
+
+// takeRetry returns, and clears, the lines that need to be sent again.
+func (jc *jobController) takeRetry() []string {
+	jc.retryMx.Lock()
+	defer jc.retryMx.Unlock()
+	r := jc.retry
+	jc.retry = nil
+	if len(r) > 0 {
+		jc.updateStatus(func(s *JobStatus) { s.Requeued -= len(r) })
+	}
+	return r
+}
+
+func (jc *jobController) linesInFlight() int {
+	jc.retryMx.Lock()
+	defer jc.retryMx.Unlock()
+	return jc.inFlight
+}
+
+// unconfirmed returns true if a command failed because the connection to SPJS or the serial port
+// went away, rather than being rejected, so the job should hold instead of failing.
+func (jc *jobController) unconfirmed(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrConnectionLost) || !jc.Connected()
+}
+
+func (jc *jobController) unheld() <-chan struct{} {
+	jc.holdMx.Lock()
+	defer jc.holdMx.Unlock()
+	return jc.unheldCh
+}
+
+// hold will stop sending new commands until resumed. It returns false if the job was already held.
+func (jc *jobController) hold(reason string) bool {
+	jc.holdMx.Lock()
+	defer jc.holdMx.Unlock()
+
+	select {
+	case <-jc.unheldCh:
+	default:
+		return false
+	}
+
+	jc.unheldCh = make(chan struct{})
+	jc.updateStatus(func(s *JobStatus) {
+		s.Held = true
+		s.HoldReason = reason
+	})
+	return true
+}
+
+// resume will continue sending commands after a hold.
+func (jc *jobController) resume() {
+	jc.holdMx.Lock()
+	defer jc.holdMx.Unlock()
+
+	select {
+	case <-jc.unheldCh:
+		return
+	default:
+	}
+
+	close(jc.unheldCh)
+	jc.updateStatus(func(s *JobStatus) {
+		s.Held = false
+		s.HoldReason = ""
+	})
+}
+
+func (jc *jobController) status() JobStatus {
+	stat := <-jc.statusCh
+	jc.statusCh <- stat
+	return stat
+}
+
 func (jc *jobController) Err() error {
 	stat := <-jc.statusCh
 	jc.statusCh <- stat
@@ -10,5 +10,23 @@ type JobStatus struct {
 	Sent         int
 	Completed    int
 
+	// Held is set when the job was paused by the watchdog, it will not continue until confirmed by the operator.
+	Held       bool
+	HoldReason string
+
+	// Done is set once every line has been sent and resolved.
+	Done bool
+
+	// Unconfirmed is the number of lines written to the controller whose completion was lost along with
+	// the SPJS connection. They may or may not have run, and are not sent again.
+	Unconfirmed int
+
+	// Requeued is the number of lines waiting to be sent again, as they were never written to the controller
+	// before the SPJS connection was lost. They are sent once the job is confirmed.
+	Requeued int
+
+	// Queued is the number of commands SPJS reported as still queued after the job was held.
+	Queued int
+
 	Err error
 }
@@ -0,0 +1,278 @@
+package spjs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Marlin is a driver for Marlin firmware, as used by 3D-printer based CNC machines and plotters.
+type Marlin struct {
+	port *Port
+
+	modalMx sync.Mutex
+	modal   marlinModal
+
+	*statusPoller
+
+	statCh    chan MarlinStatus
+	statExtCh chan ControllerStatus
+}
+
+var _ Driver = &Marlin{}
+
+func NewMarlin() *Marlin {
+	m := &Marlin{
+		statCh:       make(chan MarlinStatus, 1),
+		statExtCh:    make(chan ControllerStatus),
+		statusPoller: newStatusPoller("Marlin", "M114\n"),
+	}
+	m.statCh <- MarlinStatus{}
+	return m
+}
+
+// SetPort will start polling for position reports on the port.
+//
+// Marlin has no realtime status command, `M114` is queued like any other command, so it is only
+// sent while nothing else is queued. StatusDeferred is true while it is held back, e.g. during a job.
+func (m *Marlin) SetPort(p *Port) {
+	m.port = p
+	m.statusPoller.ready = func() bool { return p.QueueCount() == 0 }
+	go func() {
+		for e := range p.Events() {
+			if e.Type != PortOpened {
+				continue
+			}
+			m.statusPoller.reset()
+			m.update(func(stat *MarlinStatus) { *stat = MarlinStatus{} })
+			m.setModal(marlinModal{})
+		}
+	}()
+	go m.statusPoller.run(p.cli.ctx, p)
+}
+
+// Name will always return the string `Marlin`.
+func (m *Marlin) Name() string { return "Marlin" }
+
+// BufferAlgorithm returns the string `marlin`.
+func (m *Marlin) BufferAlgorithm() string { return "marlin" }
+
+// BaudRate is always set to 250000, the Marlin default.
+func (m *Marlin) BaudRate() int { return 250000 }
+
+func (m *Marlin) WrapGCode(data []string) string { return strings.Join(data, "\n") + "\n" }
+
+// Home uses `G28`, followed by `M400` so the command completes once homing has finished.
+func (m *Marlin) Home() string { return "G28\nM400\n" }
+
+// EStop uses `M112`, Marlin must be restarted afterwards.
+func (m *Marlin) EStop() string { return "M112\n" }
+
+// Reset uses `M410` to stop all motion and drop planned moves.
+//
+// Marlin only handles it immediately when built with `EMERGENCY_PARSER`, otherwise it waits its turn in the queue.
+func (m *Marlin) Reset() string { return "M410\n" }
+
+// JogCancel uses `M410` the same as Reset, with the same `EMERGENCY_PARSER` caveat.
+func (m *Marlin) JogCancel() string { return "M410\n" }
+
+// Jog uses a relative move followed by `M400`, so the command completes once the move has finished.
+//
+// No feed rate is given, as it would replace the modal feed rate of a job. Marlin uses `G0_FEEDRATE` if
+// it was built with one, otherwise the last feed rate.
+//
+// Marlin can't report its modal state, so moves are sent in the units last selected by commands sent
+// through the port, and the distance mode is restored the same way.
+func (m *Marlin) Jog(moves ...AxisMove) string {
+	modal := m.modalState()
+
+	var buf strings.Builder
+	buf.WriteString("G91\nG0")
+	for _, mv := range moves {
+		buf.WriteString(modal.units.axisWord(mv.Axis, mv.MM))
+	}
+	buf.WriteString("\n")
+	if !modal.incremental {
+		buf.WriteString("G90\n")
+	}
+	buf.WriteString("M400\n")
+	return buf.String()
+}
+
+// JogFeed is used for MPG jogs. Unlike Jog there is no `M400`, so the `M410` from JogCancel isn't
+// queued behind the move it is meant to stop.
+//
+// The feed rate is modal on Marlin, so the last feed rate sent through the port is restored afterwards.
+func (m *Marlin) JogFeed(feed float64, moves ...AxisMove) string {
+	modal := m.modalState()
+
+	var buf strings.Builder
+	buf.WriteString("G91\nG1")
+	for _, mv := range moves {
+		buf.WriteString(modal.units.axisWord(mv.Axis, mv.MM))
+	}
+	buf.WriteString(modal.units.feedWord(feed))
+	buf.WriteString("\nG1")
+	buf.WriteString(modal.units.feedWord(modal.feedRate()))
+	buf.WriteString("\n")
+	if !modal.incremental {
+		buf.WriteString("G90\n")
+	}
+	return buf.String()
+}
+
+// WPos uses `G92`, as Marlin is usually built without work coordinate systems (`G10`). The value is
+// sent in the modal units, the same as Jog.
+func (m *Marlin) WPos(axis rune, mm float64) string {
+	return "G92" + m.modalState().units.axisWord(axis, mm) + "\n"
+}
+
+// marlinModal is the modal state set by commands sent to Marlin. The zero value is the Marlin default
+// after a restart.
+type marlinModal struct {
+	units       Units
+	incremental bool
+
+	// feed is the last feed rate in mm/min, zero if none was sent
+	feed float64
+}
+
+// marlinDefaultFeed is the feed rate (mm/min) Marlin uses until one is set.
+const marlinDefaultFeed = 1500
+
+func (modal marlinModal) feedRate() float64 {
+	if modal.feed == 0 {
+		return marlinDefaultFeed
+	}
+	return modal.feed
+}
+
+var marlinWord = regexp.MustCompile(`([A-Z])([-+]?[0-9]*\.?[0-9]+)`)
+
+// sent will update the modal state from units (`G20`/`G21`), distance mode (`G90`/`G91`) and feed rate
+// (`F`) words. Feed rates on `M` commands are ignored, as they are parameters rather than the modal feed rate.
+func (m *Marlin) sent(command string) {
+	m.modalMx.Lock()
+	defer m.modalMx.Unlock()
+
+	for _, line := range strings.Split(command, "\n") {
+		if i := strings.IndexAny(line, ";("); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.ToUpper(strings.ReplaceAll(line, " ", ""))
+		if strings.HasPrefix(line, "M") {
+			continue
+		}
+		words := marlinWord.FindAllStringSubmatch(line, -1)
+		for _, w := range words {
+			if w[1] != "G" {
+				continue
+			}
+			switch w[2] {
+			case "20":
+				m.modal.units = Inches
+			case "21":
+				m.modal.units = Millimeters
+			case "90":
+				m.modal.incremental = false
+			case "91":
+				m.modal.incremental = true
+			}
+		}
+		// units apply to the whole line, so the feed rate is converted after they are known
+		for _, w := range words {
+			if w[1] != "F" {
+				continue
+			}
+			f, err := strconv.ParseFloat(w[2], 64)
+			if err == nil && f > 0 {
+				m.modal.feed = m.modal.units.ToMM(f)
+			}
+		}
+	}
+}
+
+func (m *Marlin) modalState() marlinModal {
+	m.modalMx.Lock()
+	defer m.modalMx.Unlock()
+	return m.modal
+}
+
+func (m *Marlin) setModal(modal marlinModal) {
+	m.modalMx.Lock()
+	defer m.modalMx.Unlock()
+	m.modal = modal
+}
+
+func (m *Marlin) SpindleOn(rpm float64, ccw bool) string {
+	if ccw {
+		return fmt.Sprintf("M4S%.f\n", rpm)
+	}
+	return fmt.Sprintf("M3S%.f\n", rpm)
+}
+func (m *Marlin) SpindleOff() string { return "M5\n" }
+
+// CurrentStatus returns the last status, it is always available.
+func (m *Marlin) CurrentStatus() (ControllerStatus, bool) {
+	stat := <-m.statCh
+	m.statCh <- stat
+	return stat, true
+}
+
+// Status will return a channel that will get updates each time status data is updated. It always returns the same channel.
+func (m *Marlin) Status() <-chan ControllerStatus { return m.statExtCh }
+
+func (m *Marlin) update(fn func(*MarlinStatus)) {
+	stat := <-m.statCh
+	fn(&stat)
+	m.statCh <- stat
+
+	select {
+	case m.statExtCh <- stat:
+	default:
+	}
+}
+
+// HandleData will process data coming from Marlin. It is only intended to be used by the SPJS client code.
+func (m *Marlin) HandleData(ctx context.Context, data string) error {
+	data = strings.TrimSpace(data)
+	switch {
+	case isMarlinPosition(data):
+		var err error
+		m.update(func(stat *MarlinStatus) {
+			err = stat.parsePosition(data)
+			stat.Busy = m.port != nil && m.port.QueueCount() > 0
+		})
+		if err != nil {
+			return err
+		}
+		m.statusPoller.reported(m.port != nil && m.port.QueueCount() > 0)
+	case strings.HasPrefix(data, "ok"):
+		// completion is tracked by SPJS, only the busy state needs updating
+		if m.port != nil && m.port.QueueCount() == 0 {
+			m.update(func(stat *MarlinStatus) { stat.Busy = false })
+		}
+	case strings.HasPrefix(data, "echo:busy:"), strings.HasPrefix(data, "busy:"):
+		m.update(func(stat *MarlinStatus) { stat.Busy = true })
+		m.statusPoller.reported(true)
+	case strings.HasPrefix(data, "Error:"):
+		msg := strings.TrimPrefix(data, "Error:")
+		log.Println("ERROR: Marlin:", msg)
+		m.update(func(stat *MarlinStatus) {
+			stat.LastError = msg
+			if strings.Contains(msg, "halted") || strings.Contains(msg, "kill()") {
+				stat.Halted = true
+			}
+		})
+	case strings.HasPrefix(data, "start"):
+		// printed after a restart, which also clears a halt and resets the modal state
+		m.update(func(stat *MarlinStatus) { *stat = MarlinStatus{} })
+		m.setModal(marlinModal{})
+	}
+
+	return nil
+}
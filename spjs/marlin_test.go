@@ -0,0 +1,28 @@
+package spjs
+
+import "testing"
+
+func TestMarlinSent(t *testing.T) {
+	for _, c := range []struct {
+		commands []string
+		want     marlinModal
+	}{
+		{commands: []string{"G0 X1 Y2\n"}, want: marlinModal{}},
+		{commands: []string{"G20\n"}, want: marlinModal{units: Inches}},
+		{commands: []string{"g20 g91\n"}, want: marlinModal{units: Inches, incremental: true}},
+		{commands: []string{"G91\n", "G90 ; back to absolute\n"}, want: marlinModal{}},
+		{commands: []string{"G91 (relative)\n"}, want: marlinModal{incremental: true}},
+		{commands: []string{"G1 X10 F600\n"}, want: marlinModal{feed: 600}},
+		{commands: []string{"G1 F10 G20\n"}, want: marlinModal{units: Inches, feed: 254}},
+		{commands: []string{"G1 F600\nM203 X100 F50\n"}, want: marlinModal{feed: 600}},
+		{commands: []string{"G20\n", "G21\nG1 F300\n"}, want: marlinModal{feed: 300}},
+	} {
+		var m Marlin
+		for _, cmd := range c.commands {
+			m.sent(cmd)
+		}
+		if got := m.modalState(); got != c.want {
+			t.Errorf("%q: got %+v; want %+v", c.commands, got, c.want)
+		}
+	}
+}
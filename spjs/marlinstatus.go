@@ -0,0 +1,88 @@
+package spjs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MarlinStatus is the state of a Marlin controller, built from `M114` position reports and responses.
+type MarlinStatus struct {
+	// Pos is the logical position from `M114`. Marlin does not report machine and work positions separately.
+	Pos Position
+
+	// Axes is the number of axes included in position reports.
+	Axes int
+
+	// Busy is true while commands are queued or Marlin reports `busy:`.
+	Busy bool
+
+	// Halted is true after Marlin has been killed (e.g. by `M112`), until it is restarted.
+	Halted bool
+
+	// LastError is the most recent `Error:` response.
+	LastError string
+}
+
+var _ ControllerStatus = MarlinStatus{}
+
+func (stat MarlinStatus) IsAlarm() bool             { return stat.Halted }
+func (stat MarlinStatus) IsReady() bool             { return !stat.Busy && !stat.Halted }
+func (stat MarlinStatus) MachinePosition() Position { return stat.Pos }
+func (stat MarlinStatus) WorkPosition() Position    { return stat.Pos }
+
+// FeedRate always returns 0, Marlin does not report the current feed rate.
+func (stat MarlinStatus) FeedRate() float64 { return 0 }
+
+// Accessories always returns an empty status, Marlin does not report output state.
+func (stat MarlinStatus) Accessories() AccessoryStatus { return AccessoryStatus{} }
+
+func (stat MarlinStatus) StatusText() string {
+	switch {
+	case stat.Halted:
+		return "Halted"
+	case stat.Busy:
+		return "Busy"
+	}
+	return "Idle"
+}
+
+func (stat MarlinStatus) AxisCount() int {
+	if stat.Axes == 0 {
+		return 3
+	}
+	return stat.Axes
+}
+
+// isMarlinPosition returns true if the line is a position report (e.g. `X:0.00 Y:0.00 Z:0.00 E:0.00 Count X:0 Y:0 Z:0`).
+func isMarlinPosition(data string) bool {
+	return strings.HasPrefix(data, "X:") && strings.Contains(data, "Y:")
+}
+
+// parsePosition will parse an `M114` report. Stepper counts and the extruder position are ignored.
+func (stat *MarlinStatus) parsePosition(data string) error {
+	if i := strings.Index(data, "Count"); i >= 0 {
+		data = data[:i]
+	}
+
+	var pos Position
+	var axes int
+	for _, field := range strings.Fields(data) {
+		p := strings.SplitN(field, ":", 2)
+		if len(p) != 2 || len(p[0]) != 1 || !strings.Contains(Axes, p[0]) {
+			continue
+		}
+		val, err := strconv.ParseFloat(p[1], 64)
+		if err != nil {
+			return fmt.Errorf("parse %s '%s': %w", p[0], p[1], err)
+		}
+		pos.SetAxis(rune(p[0][0]), val)
+		if n := strings.Index(Axes, p[0]) + 1; n > axes {
+			axes = n
+		}
+	}
+
+	stat.Pos = pos
+	stat.Axes = axes
+	return nil
+}
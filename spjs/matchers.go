@@ -0,0 +1,47 @@
+package spjs
+
+import "regexp"
+
+// NewSerialNumberMatcher returns a SerialPortMatcher that returns true if the usb serial number matches exactly.
+func NewSerialNumberMatcher(serial string) SerialPortMatcher {
+	return func(sp SerialPort) bool { return sp.SerialNumber == serial }
+}
+
+// NewNameMatcher returns a SerialPortMatcher that returns true if the port name (e.g. `/dev/ttyUSB0`) matches the expression.
+func NewNameMatcher(expr *regexp.Regexp) SerialPortMatcher {
+	return func(sp SerialPort) bool { return expr.MatchString(sp.Name) }
+}
+
+// NewFriendlyMatcher returns a SerialPortMatcher that returns true if the friendly port name matches the expression.
+func NewFriendlyMatcher(expr *regexp.Regexp) SerialPortMatcher {
+	return func(sp SerialPort) bool { return expr.MatchString(sp.Friendly) }
+}
+
+// NewAndMatcher returns a SerialPortMatcher that returns true if all provided matchers return true.
+func NewAndMatcher(matchers ...SerialPortMatcher) SerialPortMatcher {
+	return func(sp SerialPort) bool {
+		for _, m := range matchers {
+			if !m(sp) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NewOrMatcher returns a SerialPortMatcher that returns true if any of the provided matchers return true.
+func NewOrMatcher(matchers ...SerialPortMatcher) SerialPortMatcher {
+	return func(sp SerialPort) bool {
+		for _, m := range matchers {
+			if m(sp) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// NewNotMatcher returns a SerialPortMatcher that inverts the result of the provided matcher.
+func NewNotMatcher(m SerialPortMatcher) SerialPortMatcher {
+	return func(sp SerialPort) bool { return !m(sp) }
+}
@@ -0,0 +1,133 @@
+package spjs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnknownMessage is returned when a message from SPJS is valid, but not of a supported kind.
+var ErrUnknownMessage = errors.New("unknown SPJS message")
+
+// VersionMessage is sent by SPJS when a client connects.
+type VersionMessage struct {
+	Version  string
+	Commands []string
+	Hostname string
+}
+
+// PortListMessage is the response to the `list` command.
+type PortListMessage struct {
+	SerialPorts []SerialPort
+}
+
+// SerialDataMessage is data read from a serial port.
+type SerialDataMessage struct {
+	P string
+	D string
+}
+
+// CommandMessage reports the progress of a command or a change in port state (e.g. `Write`, `Complete`, `Open`).
+type CommandMessage struct {
+	Cmd       string
+	ID        string   `json:"Id"`
+	IDs       []string `json:"Ids"`
+	P         string
+	Port      string
+	QCnt      int
+	ErrorCode string
+	Desc      string
+}
+
+// PortName returns the serial port the command applies to.
+func (m CommandMessage) PortName() string {
+	if m.P != "" {
+		return m.P
+	}
+	return m.Port
+}
+
+// TextMessage is a plain text reply from SPJS, sent for some commands instead of JSON.
+type TextMessage string
+
+// ErrorMessage is an error reported by SPJS that is not tied to a command.
+type ErrorMessage struct {
+	Error string
+}
+
+// BadMessage is a message from SPJS that could not be decoded.
+type BadMessage struct {
+	Time time.Time
+	Data string
+	Err  error
+}
+
+// BadMessages returns a channel that receives every message that could not be decoded. It always returns the same channel.
+//
+// Messages are dropped if the channel is not read fast enough.
+func (c *Client) BadMessages() <-chan BadMessage { return c.badMsgCh }
+
+// BadMessageCounts returns the number of unknown and invalid messages received from SPJS.
+func (c *Client) BadMessageCounts() (unknown, invalid int) {
+	c.badMsgMx.Lock()
+	defer c.badMsgMx.Unlock()
+	return c.unknownMsgs, c.invalidMsgs
+}
+
+func (c *Client) badMessage(data string, err error) {
+	c.badMsgMx.Lock()
+	if errors.Is(err, ErrUnknownMessage) {
+		c.unknownMsgs++
+	} else {
+		c.invalidMsgs++
+	}
+	c.badMsgMx.Unlock()
+
+	select {
+	case c.badMsgCh <- BadMessage{Time: time.Now(), Data: data, Err: err}:
+	default:
+	}
+}
+
+// decodeMessage will decode a message from SPJS into one of the known message types.
+func decodeMessage(data string) (interface{}, error) {
+	// SPJS also sends plain text replies to some commands
+	if !strings.HasPrefix(strings.TrimSpace(data), "{") {
+		return TextMessage(strings.TrimSpace(data)), nil
+	}
+
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal([]byte(data), &fields)
+	if err != nil {
+		return nil, fmt.Errorf("decode SPJS message: %w", err)
+	}
+	has := func(name string) bool {
+		_, ok := fields[name]
+		return ok
+	}
+
+	var msg interface{}
+	switch {
+	case has("SerialPorts"):
+		msg = &PortListMessage{}
+	case has("Version"):
+		msg = &VersionMessage{}
+	case has("Cmd"):
+		msg = &CommandMessage{}
+	case has("P") && has("D"):
+		msg = &SerialDataMessage{}
+	case has("Error"):
+		msg = &ErrorMessage{}
+	default:
+		return nil, ErrUnknownMessage
+	}
+
+	err = json.Unmarshal([]byte(data), msg)
+	if err != nil {
+		return nil, fmt.Errorf("decode SPJS message (%T): %w", msg, err)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,192 @@
+package spjs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// mpgSlice is how long wheel detents are collected before being sent as a single jog.
+	mpgSlice = 50 * time.Millisecond
+
+	// mpgMaxOutstanding is the max number of jog commands waiting to be accepted by the controller.
+	mpgMaxOutstanding = 2
+
+	// mpgStopAfter is how long without detents before the wheel is considered stopped.
+	mpgStopAfter = 150 * time.Millisecond
+
+	// mpgMinFeed and mpgMaxFeed limit the jog feed rate (mm/min).
+	mpgMinFeed = 50
+	mpgMaxFeed = 6000
+)
+
+// mpgJogger turns wheel detents into jog commands. Detents are coalesced per time slice, and the
+// feed rate is set so each jog takes about as long as the wheel took to turn, so the machine follows
+// the wheel instead of building a backlog. The jog is canceled as soon as the wheel stops.
+//
+// Drivers that can't cancel jogs must complete a jog once the move has finished (e.g. with `M400`),
+// and only one is sent at a time, so the machine stops within a slice of the wheel stopping.
+type mpgJogger struct {
+	ctrl *Controller
+
+	mx          sync.Mutex
+	pending     map[rune]float64
+	lastDetent  time.Time
+	lastSend    time.Time
+	outstanding int
+	moving      bool
+
+	// sources are the inputs that added detents to the current motion
+	sources map[interface{}]bool
+}
+
+func newMPGJogger(ctrl *Controller) *mpgJogger {
+	return &mpgJogger{ctrl: ctrl, pending: make(map[rune]float64), sources: make(map[interface{}]bool)}
+}
+
+// jogger returns the MPG engine for the controller, starting it on first use. It is shared by all
+// inputs, so they don't send competing jogs.
+func (c *Controller) jogger() *mpgJogger {
+	c.mpgOnce.Do(func() {
+		c.mpg = newMPGJogger(c)
+		go c.mpg.run(c.cli.ctx)
+	})
+	return c.mpg
+}
+
+// add will queue detents from the source input to be sent with the next time slice.
+func (j *mpgJogger) add(source interface{}, steps []pendantStep) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	j.sources[source] = true
+	for _, s := range steps {
+		j.pending[s.Axis] += s.move().MM
+	}
+	j.lastDetent = time.Now()
+}
+
+// stop will discard pending detents and stop any jog in progress, if the source input added to it. Motion
+// from other inputs is left alone. It returns true if the source was moving.
+//
+// The machine can't stop one input's motion alone, so everything is stopped. Other inputs still jogging
+// start again with their next detents.
+func (j *mpgJogger) stop(ctx context.Context, source interface{}) (bool, error) {
+	j.mx.Lock()
+	wasMoving := j.sources[source] && (j.moving || len(j.pending) > 0)
+	if wasMoving {
+		j.pending = make(map[rune]float64)
+		j.moving = false
+		j.sources = make(map[interface{}]bool)
+	}
+	j.mx.Unlock()
+
+	if !wasMoving {
+		return false, nil
+	}
+
+	return true, j.cancel(ctx)
+}
+
+// cancelable returns true if the driver can cancel jogs.
+func (j *mpgJogger) cancelable() bool {
+	_, ok := j.ctrl.Driver().(JogCanceler)
+	return ok
+}
+
+// cancel will stop the current jog. Nothing is sent if the driver can't cancel jogs, as only the last
+// slice is outstanding and it finishes on its own. Without feed rate jogs a slice may take longer than
+// the wheel took to turn, so that is reported as unsupported.
+func (j *mpgJogger) cancel(ctx context.Context) error {
+	if j.cancelable() {
+		return j.ctrl.CommandJogCancel(ctx)
+	}
+	if _, ok := j.ctrl.Driver().(FeedJoggable); !ok {
+		return fmt.Errorf("stop jog: %w", ErrUnsupportedByDriver)
+	}
+	return nil
+}
+
+func (j *mpgJogger) run(ctx context.Context) {
+	t := time.NewTicker(mpgSlice)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+
+		j.tick(ctx)
+	}
+}
+
+func (j *mpgJogger) tick(ctx context.Context) {
+	j.mx.Lock()
+	if len(j.pending) == 0 {
+		stopped := j.moving && j.outstanding == 0 && time.Since(j.lastDetent) > mpgStopAfter
+		if stopped {
+			j.moving = false
+			j.sources = make(map[interface{}]bool)
+		}
+		j.mx.Unlock()
+
+		if stopped {
+			err := j.cancel(ctx)
+			if err != nil {
+				log.Println("ERROR: cancel MPG jog:", err)
+			}
+		}
+		return
+	}
+	maxOutstanding := mpgMaxOutstanding
+	if !j.cancelable() {
+		maxOutstanding = 1
+	}
+	if j.outstanding >= maxOutstanding {
+		// keep collecting until the controller catches up
+		j.mx.Unlock()
+		return
+	}
+
+	var moves []AxisMove
+	var dist float64
+	for axis, mm := range j.pending {
+		if mm == 0 {
+			continue
+		}
+		moves = append(moves, AxisMove{Axis: axis, MM: mm})
+		dist += mm * mm
+	}
+	j.pending = make(map[rune]float64)
+	if len(moves) == 0 {
+		j.mx.Unlock()
+		return
+	}
+	// detents may have been collected over several slices while waiting for the controller
+	elapsed := mpgSlice
+	if j.moving && time.Since(j.lastSend) > elapsed {
+		elapsed = time.Since(j.lastSend)
+	}
+	j.lastSend = time.Now()
+	j.outstanding++
+	j.moving = true
+	j.mx.Unlock()
+
+	// cover the distance in about the time it took to turn the wheel, so faster wheel turns move faster
+	feed := math.Sqrt(dist) / elapsed.Minutes()
+	feed = math.Max(mpgMinFeed, math.Min(mpgMaxFeed, feed))
+
+	go func() {
+		err := j.ctrl.CommandJogFeed(ctx, moves, feed, true)
+		if err != nil {
+			log.Println("ERROR: MPG jog:", err)
+		}
+		j.mx.Lock()
+		j.outstanding--
+		j.mx.Unlock()
+	}()
+}
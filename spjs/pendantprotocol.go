@@ -0,0 +1,258 @@
+package spjs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Pendant actions that can be bound to a button. A work position can be zeroed with `zero:<axis>` (e.g. `zero:X`).
+const (
+	PendantEStop       = "estop"
+	PendantFeedHold    = "feedhold"
+	PendantCycleStart  = "cyclestart"
+	PendantReset       = "reset"
+	PendantHome        = "home"
+	PendantSpindleOff  = "spindleoff"
+	PendantToggleFlood = "flood"
+	PendantToggleMist  = "mist"
+
+	pendantZeroPrefix = "zero:"
+)
+
+// PendantProtocol describes the messages a pendant sends, and how they map to controller commands.
+type PendantProtocol struct {
+	// Step matches a step message. The first submatch, or the whole message if there is none, holds
+	// `;`-separated groups of `<axis>,<mult>,<step>`.
+	Step string `json:"step"`
+
+	// Scale converts `mult * step` to mm, for axes without their own scale.
+	Scale float64 `json:"scale"`
+
+	// Axes maps the axis number in step messages to a machine axis.
+	Axes map[int]PendantAxis `json:"axes"`
+
+	Buttons []PendantButton `json:"buttons"`
+
+	// Display matches the message a pendant sends to announce it has a display. Once seen, DRO lines are sent to it.
+	Display string `json:"display"`
+
+	// DisplayMillis is the minimum time between DRO lines.
+	DisplayMillis int `json:"displayMillis"`
+
+	step    *regexp.Regexp
+	display *regexp.Regexp
+}
+
+// PendantAxis is the machine axis for an axis number in step messages.
+type PendantAxis struct {
+	Axis   string  `json:"axis"`
+	Invert bool    `json:"invert"`
+	Scale  float64 `json:"scale"`
+}
+
+// PendantButton runs an action when a message matches.
+type PendantButton struct {
+	Match  string `json:"match"`
+	Action string `json:"action"`
+
+	match *regexp.Regexp
+}
+
+// DefaultPendantProtocol returns the protocol used by the original Arduino pendant firmware.
+func DefaultPendantProtocol() *PendantProtocol {
+	pp := &PendantProtocol{
+		Step:  `^STEP:(.*)$`,
+		Scale: 0.01,
+		Axes: map[int]PendantAxis{
+			1: {Axis: "X"},
+			2: {Axis: "Y"},
+			3: {Axis: "Z", Invert: true},
+			4: {Axis: "A"},
+			5: {Axis: "B"},
+			6: {Axis: "C"},
+		},
+		Buttons: []PendantButton{
+			{Match: `^STOP$`, Action: PendantEStop},
+		},
+		Display: `^DISPLAY$`,
+	}
+	err := pp.compile()
+	if err != nil {
+		panic(err)
+	}
+	return pp
+}
+
+// LoadPendantProtocol will read a JSON pendant protocol.
+func LoadPendantProtocol(r io.Reader) (*PendantProtocol, error) {
+	var pp PendantProtocol
+	err := json.NewDecoder(r).Decode(&pp)
+	if err != nil {
+		return nil, fmt.Errorf("decode pendant protocol: %w", err)
+	}
+
+	err = pp.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pp, nil
+}
+
+func (pp *PendantProtocol) compile() error {
+	var err error
+	if pp.Step != "" {
+		pp.step, err = regexp.Compile(pp.Step)
+		if err != nil {
+			return fmt.Errorf("step pattern: %w", err)
+		}
+	}
+	if pp.Display != "" {
+		pp.display, err = regexp.Compile(pp.Display)
+		if err != nil {
+			return fmt.Errorf("display pattern: %w", err)
+		}
+	}
+	if pp.Scale == 0 {
+		pp.Scale = 0.01
+	}
+	if pp.DisplayMillis <= 0 {
+		pp.DisplayMillis = 250
+	}
+
+	for n, a := range pp.Axes {
+		if len(a.Axis) != 1 || !strings.Contains(Axes, a.Axis) {
+			return fmt.Errorf("axis %d: unknown axis '%s'", n, a.Axis)
+		}
+	}
+
+	for i, b := range pp.Buttons {
+		pp.Buttons[i].match, err = regexp.Compile(b.Match)
+		if err != nil {
+			return fmt.Errorf("button '%s': %w", b.Match, err)
+		}
+		err = validPendantAction(b.Action)
+		if err != nil {
+			return fmt.Errorf("button '%s': %w", b.Match, err)
+		}
+	}
+
+	return nil
+}
+
+func validPendantAction(action string) error {
+	switch action {
+	case PendantEStop, PendantFeedHold, PendantCycleStart, PendantReset, PendantHome,
+		PendantSpindleOff, PendantToggleFlood, PendantToggleMist:
+		return nil
+	}
+	if axis := strings.TrimPrefix(action, pendantZeroPrefix); axis != action && len(axis) == 1 && strings.Contains(Axes, axis) {
+		return nil
+	}
+	return fmt.Errorf("unknown action '%s'", action)
+}
+
+// isDisplay returns true if the message announces a display.
+func (pp *PendantProtocol) isDisplay(msg string) bool {
+	return pp.display != nil && pp.display.MatchString(msg)
+}
+
+// button returns the action for the first button matching the message, if any.
+func (pp *PendantProtocol) button(msg string) (string, bool) {
+	for _, b := range pp.Buttons {
+		if b.match.MatchString(msg) {
+			return b.Action, true
+		}
+	}
+	return "", false
+}
+
+// pendantStep is a number of wheel detents on a single axis.
+type pendantStep struct {
+	Axis rune
+
+	// Detents is the signed number of detents, after inversion.
+	Detents int
+
+	// Size is the distance of a single detent in mm.
+	Size float64
+}
+
+func (s pendantStep) move() AxisMove { return AxisMove{Axis: s.Axis, MM: float64(s.Detents) * s.Size} }
+
+// parseStep will return the steps for a step message, ok is false if it isn't one.
+func (pp *PendantProtocol) parseStep(msg string) (steps []pendantStep, ok bool, err error) {
+	if pp.step == nil {
+		return nil, false, nil
+	}
+	m := pp.step.FindStringSubmatch(msg)
+	if m == nil {
+		return nil, false, nil
+	}
+	groups := m[0]
+	if len(m) > 1 {
+		groups = m[1]
+	}
+
+	for _, group := range strings.Split(groups, ";") {
+		// a trailing `;` or a message with no axes moving leaves empty groups
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+
+		var axisIndex, mult, step int
+		_, err := fmt.Sscanf(group, "%d,%d,%d", &axisIndex, &mult, &step)
+		if err != nil {
+			return nil, true, err
+		}
+
+		a, ok := pp.Axes[axisIndex]
+		if !ok {
+			continue
+		}
+		if a.Invert {
+			step = -step
+		}
+		scale := a.Scale
+		if scale == 0 {
+			scale = pp.Scale
+		}
+
+		steps = append(steps, pendantStep{Axis: rune(a.Axis[0]), Detents: step, Size: float64(mult) * scale})
+	}
+
+	return steps, true, nil
+}
+
+// runPendantAction will run a button action on the controller. It may wait for the command to complete,
+// so it must not be called from HandleData directly.
+func runPendantAction(ctx context.Context, ctrl *Controller, action string) error {
+	switch action {
+	case PendantEStop:
+		return ctrl.CommandEStop(ctx)
+	case PendantFeedHold:
+		return ctrl.CommandFeedHold(ctx)
+	case PendantCycleStart:
+		return ctrl.CommandCycleStart(ctx)
+	case PendantReset:
+		return ctrl.CommandReset(ctx)
+	case PendantHome:
+		return ctrl.CommandHome(ctx, false)
+	case PendantSpindleOff:
+		return ctrl.CommandSpindleOff(ctx)
+	case PendantToggleFlood:
+		return ctrl.CommandToggleFlood(ctx)
+	case PendantToggleMist:
+		return ctrl.CommandToggleMist(ctx)
+	}
+	if axis := strings.TrimPrefix(action, pendantZeroPrefix); axis != action && len(axis) == 1 {
+		return ctrl.SetWPos(ctx, rune(axis[0]), 0)
+	}
+
+	return fmt.Errorf("unknown action '%s'", action)
+}
@@ -0,0 +1,48 @@
+package spjs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPendantProtocolParseStep(t *testing.T) {
+	pp := DefaultPendantProtocol()
+	pp.Axes[2] = PendantAxis{Axis: "Y", Scale: 0.1}
+
+	for _, c := range []struct {
+		msg   string
+		ok    bool
+		steps []pendantStep
+	}{
+		{msg: "STEP:1,1,2", ok: true, steps: []pendantStep{{Axis: 'X', Detents: 2, Size: 0.01}}},
+		{msg: "STEP:1,10,-1", ok: true, steps: []pendantStep{{Axis: 'X', Detents: -1, Size: 0.1}}},
+		{msg: "STEP:3,1,1", ok: true, steps: []pendantStep{{Axis: 'Z', Detents: -1, Size: 0.01}}},
+		{msg: "STEP:2,100,3", ok: true, steps: []pendantStep{{Axis: 'Y', Detents: 3, Size: 10}}},
+		{msg: "STEP:1,1,1;4,10,-2", ok: true, steps: []pendantStep{
+			{Axis: 'X', Detents: 1, Size: 0.01},
+			{Axis: 'A', Detents: -2, Size: 0.1},
+		}},
+		{msg: "STEP:9,1,1", ok: true},
+		{msg: "STEP:1,1,1;", ok: true, steps: []pendantStep{{Axis: 'X', Detents: 1, Size: 0.01}}},
+		{msg: "STEP:;2,1,1", ok: true, steps: []pendantStep{{Axis: 'Y', Detents: 1, Size: 0.1}}},
+		{msg: "STEP:", ok: true},
+		{msg: "STOP"},
+		{msg: "DISPLAY"},
+	} {
+		steps, ok, err := pp.parseStep(c.msg)
+		if err != nil {
+			t.Errorf("%s: %v", c.msg, err)
+			continue
+		}
+		if ok != c.ok || !reflect.DeepEqual(steps, c.steps) {
+			t.Errorf("%s: got %+v, %t; want %+v, %t", c.msg, steps, ok, c.steps, c.ok)
+		}
+	}
+
+	for _, bad := range []string{"STEP:1,x,1", "STEP:1;2"} {
+		_, ok, err := pp.parseStep(bad)
+		if !ok || err == nil {
+			t.Errorf("%s: got ok=%t err=%v; want ok and an error", bad, ok, err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package spjs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// statusPollActive and statusPollIdle are the default status poll intervals while moving and stopped.
+	statusPollActive = 200 * time.Millisecond
+	statusPollIdle   = time.Second
+
+	// statusStale is how long without a status report before it is logged as stale.
+	statusStale = 3 * time.Second
+)
+
+// statusPoller will periodically request a status report from a controller, more often while it is moving.
+//
+// Drivers embed it to provide `SetPollRate` and `StatusAge`.
+type statusPoller struct {
+	name, cmd string
+
+	// ready, if set, is checked before each poll and the poll is skipped if it returns false.
+	ready func() bool
+
+	pollMx       sync.Mutex
+	active, idle time.Duration
+	lastReport   time.Time
+	moving       bool
+	deferred     bool
+}
+
+func newStatusPoller(name, cmd string) *statusPoller {
+	return &statusPoller{
+		name:       name,
+		cmd:        cmd,
+		active:     statusPollActive,
+		idle:       statusPollIdle,
+		lastReport: time.Now(),
+	}
+}
+
+// SetPollRate will set how often status is requested while the machine is moving and while it is stopped.
+// A rate of zero disables polling in that state.
+func (sp *statusPoller) SetPollRate(active, idle time.Duration) {
+	sp.pollMx.Lock()
+	defer sp.pollMx.Unlock()
+	sp.active, sp.idle = active, idle
+}
+
+// StatusQuery returns the command used to request a status report.
+func (sp *statusPoller) StatusQuery() string { return sp.cmd }
+
+// StatusAge returns the time since the last status report, or since the port was last opened.
+func (sp *statusPoller) StatusAge() time.Duration {
+	sp.pollMx.Lock()
+	defer sp.pollMx.Unlock()
+	return time.Since(sp.lastReport)
+}
+
+// StatusDeferred returns true while polls are being skipped because the controller is not ready,
+// so the last status may be out of date without the controller being unresponsive.
+func (sp *statusPoller) StatusDeferred() bool {
+	sp.pollMx.Lock()
+	defer sp.pollMx.Unlock()
+	return sp.deferred
+}
+
+// setDeferred will record if polls are being skipped, returning the previous value.
+func (sp *statusPoller) setDeferred(deferred bool) bool {
+	sp.pollMx.Lock()
+	defer sp.pollMx.Unlock()
+	prev := sp.deferred
+	sp.deferred = deferred
+	return prev
+}
+
+// reported should be called after each status report is processed.
+func (sp *statusPoller) reported(moving bool) {
+	sp.pollMx.Lock()
+	defer sp.pollMx.Unlock()
+	sp.lastReport = time.Now()
+	sp.moving = moving
+}
+
+// reset will restart the status age, it should be called when the port is opened.
+func (sp *statusPoller) reset() {
+	sp.pollMx.Lock()
+	defer sp.pollMx.Unlock()
+	sp.lastReport = time.Now()
+}
+
+func (sp *statusPoller) interval() time.Duration {
+	sp.pollMx.Lock()
+	defer sp.pollMx.Unlock()
+	if sp.moving {
+		return sp.active
+	}
+	return sp.idle
+}
+
+// run will send the status command at the current poll rate until the context is canceled.
+func (sp *statusPoller) run(ctx context.Context, p *Port) {
+	t := time.NewTimer(0)
+	defer t.Stop()
+
+	var stale bool
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+
+		interval := sp.interval()
+		if interval <= 0 {
+			// polling disabled, check again later in case it is re-enabled or the state changes
+			t.Reset(statusPollIdle)
+			continue
+		}
+		t.Reset(interval)
+
+		if !p.Connected() {
+			stale = false
+			sp.setDeferred(false)
+			continue
+		}
+		if sp.ready != nil && !sp.ready() {
+			// the status age keeps growing, StatusDeferred tells it apart from not responding
+			sp.setDeferred(true)
+			continue
+		}
+		if sp.setDeferred(false) {
+			// not polling is not the same as not responding
+			sp.reset()
+		}
+
+		age := sp.StatusAge()
+		if age > statusStale && !stale {
+			log.Printf("WARN: no status report from %s in %s", sp.name, age.Truncate(time.Second))
+		}
+		stale = age > statusStale
+
+		err := p.sendQuiet(sp.cmd)
+		if err != nil && !errors.Is(err, ErrClientClosed) {
+			log.Printf("ERROR: poll %s status: %v", sp.name, err)
+		}
+	}
+}
@@ -6,15 +6,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
+	"sync"
 	"sync/atomic"
 )
 
 type Port struct {
-	cli   *Client
-	match func(SerialPort) bool
+	cli *Client
+
+	mx    sync.Mutex
 	drv   Driver
+	match SerialPortMatcher
+	state portState
+	baud  int
 
 	sendCh chan *sendReq
+	logCh  chan PortLogEntry
+
+	statsMx sync.Mutex
+	stats   QueueStats
+	statsCh chan struct{}
 }
 
 // Connected returns true if the serial port is available and open.
@@ -28,6 +39,7 @@ func (p *Port) SendCommand(ctx context.Context, command string, wait bool) error
 	if err != nil {
 		return err
 	}
+	p.logData(true, command)
 	if !wait {
 		return nil
 	}
@@ -46,7 +58,15 @@ type sendReq struct {
 }
 
 func (p *Port) sendLoop() {
-	for req := range p.sendCh {
+	defer p.cli.wg.Done()
+	for {
+		var req *sendReq
+		select {
+		case req = <-p.sendCh:
+		case <-p.cli.ctx.Done():
+			return
+		}
+
 		data, err := json.Marshal(SendJSON{
 			Port: req.Port,
 			Data: []SendJSONData{{ID: req.Format(p.cli.baseID), Data: req.data}},
@@ -56,7 +76,8 @@ func (p *Port) sendLoop() {
 		}
 		_, err = io.WriteString(p.cli, "sendjson "+string(data))
 		if err != nil {
-			req.cb.finish(err)
+			// the command never reached SPJS, so the job should hold rather than fail
+			req.cb.finish(fmt.Errorf("%w: %v", ErrConnectionLost, err))
 			continue
 		}
 	}
@@ -74,37 +95,130 @@ func (p *Port) sendJSON(id commandID, serialData string) *commandCallback {
 	return cb
 }
 func (p *Port) sendCommand(command string) (*commandCallback, error) {
+	id, err := p.nextID()
+	if err != nil {
+		return nil, err
+	}
+	if o, ok := p.Driver().(commandObserver); ok {
+		o.sent(command)
+	}
+
+	return p.sendJSON(id, command), nil
+}
+
+// sendQuiet will send a command without logging it or tracking completion. It is intended for frequent
+// realtime commands, like status polling, that would otherwise flood the log and callback map.
+func (p *Port) sendQuiet(command string) error {
+	id, err := p.nextID()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case p.sendCh <- &sendReq{commandID: id, data: command}:
+	case <-p.cli.ctx.Done():
+		return ErrClientClosed
+	}
+	return nil
+}
+
+func (p *Port) nextID() (commandID, error) {
+	if p.cli.ctx.Err() != nil {
+		return commandID{}, ErrClientClosed
+	}
 	portName, isOpen := p.Name()
 	if portName == "" {
-		return nil, errors.New("port not available")
+		return commandID{}, errors.New("port not available")
 	}
 
 	if !isOpen {
 		err := p.open(portName)
 		if err != nil {
-			return nil, err
+			return commandID{}, err
 		}
 	}
-	id := commandID{Port: portName, ID: atomic.AddUint32(&p.cli.id, 1)}
 
-	return p.sendJSON(id, command), nil
+	return commandID{Port: portName, ID: atomic.AddUint32(&p.cli.id, 1)}, nil
 }
 
 func (p *Port) open(name string) error {
-	_, err := fmt.Fprintf(p.cli, "open %s %d %s", name, p.drv.BaudRate(), p.drv.BufferAlgorithm())
+	drv := p.Driver()
+	_, err := fmt.Fprintf(p.cli, "open %s %d %s", name, p.BaudRate(), drv.BufferAlgorithm())
 	if err != nil {
-		return fmt.Errorf("open %s (%s): %w", name, p.drv.Name(), err)
+		return fmt.Errorf("open %s (%s): %w", name, drv.Name(), err)
 	}
 
 	return nil
 }
 
+// BaudRate returns the baud rate the port is opened at, the one set with SetBaudRate or the driver default.
+func (p *Port) BaudRate() int {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	if p.baud > 0 {
+		return p.baud
+	}
+	return p.drv.BaudRate()
+}
+
+// SetBaudRate will override the driver baud rate, a rate of zero uses the driver default again. It takes
+// effect the next time the port is opened.
+func (p *Port) SetBaudRate(baud int) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.baud = baud
+}
+
+// Driver returns the current driver for the port.
+func (p *Port) Driver() Driver {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return p.drv
+}
+
+// SetDriver will replace the port driver. If the port is open it is closed, so that it is re-opened
+// with the baud rate (unless overridden with SetBaudRate) and buffer algorithm of the new driver.
+//
+// The previous driver is not stopped, so it should not have any background work of its own.
+func (p *Port) SetDriver(drv Driver) {
+	p.mx.Lock()
+	p.drv = drv
+	state := p.state
+	p.mx.Unlock()
+
+	if s, ok := drv.(PortSetter); ok {
+		s.SetPort(p)
+	}
+	log.Println("Changed driver to", drv.Name())
+
+	if state.Open {
+		fmt.Fprintf(p.cli, "close %s", state.Name)
+		io.WriteString(p.cli, "list")
+	}
+}
+
+// SetMatcher will change which serial port is used. It takes effect on the next port list from SPJS.
+func (p *Port) SetMatcher(match SerialPortMatcher) {
+	p.mx.Lock()
+	p.match = match
+	p.mx.Unlock()
+	io.WriteString(p.cli, "list")
+}
+
+// Matches returns true if the serial port should be used by this port.
+func (p *Port) Matches(sp SerialPort) bool {
+	p.mx.Lock()
+	match := p.match
+	p.mx.Unlock()
+	return match(sp)
+}
+
 func (p *Port) Name() (string, bool) {
 	ports := <-p.cli.serialPorts
 	p.cli.serialPorts <- ports
 
 	for _, port := range ports {
-		if !p.match(port) {
+		if !p.Matches(port) {
 			continue
 		}
 		return port.Name, port.IsOpen
@@ -0,0 +1,148 @@
+package spjs
+
+import (
+	"log"
+	"time"
+)
+
+// PortEventType is the kind of transition a serial port went through.
+type PortEventType int
+
+const (
+	// PortAppeared means a matching serial port was added to the SPJS port list.
+	PortAppeared PortEventType = iota
+
+	// PortOpened means the serial port was opened by SPJS.
+	PortOpened
+
+	// PortClosed means the serial port was closed but is still available.
+	PortClosed
+
+	// PortDisappeared means the serial port is no longer in the SPJS port list (e.g. it was unplugged).
+	PortDisappeared
+
+	// PortReconnecting means the connection to SPJS was lost, port state is unknown until it is re-established.
+	PortReconnecting
+)
+
+func (t PortEventType) String() string {
+	switch t {
+	case PortAppeared:
+		return "Appeared"
+	case PortOpened:
+		return "Opened"
+	case PortClosed:
+		return "Closed"
+	case PortDisappeared:
+		return "Disappeared"
+	case PortReconnecting:
+		return "Reconnecting"
+	}
+	return "Unknown"
+}
+
+// PortEvent describes a change in the state of a serial port.
+type PortEvent struct {
+	Type PortEventType
+	Time time.Time
+
+	// Port is the registered port the event applies to.
+	Port *Port
+
+	// Name is the serial port name (e.g. `/dev/ttyUSB0`), it may be empty if unknown.
+	Name string
+
+	Reason string
+}
+
+type portState struct {
+	Name    string
+	Present bool
+	Open    bool
+}
+
+type eventSub struct {
+	ch     chan PortEvent
+	filter func(PortEvent) bool
+}
+
+// Events returns a new channel that receives events for all registered ports.
+//
+// Events are dropped if the channel is not read fast enough.
+func (c *Client) Events() <-chan PortEvent { return c.subscribe(nil) }
+
+// Events returns a new channel that receives events for this port only.
+//
+// Events are dropped if the channel is not read fast enough.
+func (p *Port) Events() <-chan PortEvent {
+	return p.cli.subscribe(func(e PortEvent) bool { return e.Port == p })
+}
+
+func (c *Client) subscribe(filter func(PortEvent) bool) <-chan PortEvent {
+	ch := make(chan PortEvent, 10)
+	c.eventSubs <- append(<-c.eventSubs, eventSub{ch: ch, filter: filter})
+	return ch
+}
+
+func (c *Client) emit(e PortEvent) {
+	e.Time = time.Now()
+	log.Printf("Port %s (%s): %s: %s", e.Type, e.Port.Driver().Name(), e.Name, e.Reason)
+
+	// hold the subscription list while sending so Close can't close a channel mid-send
+	subs := <-c.eventSubs
+	defer func() { c.eventSubs <- subs }()
+	for _, sub := range subs {
+		if sub.filter != nil && !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	}
+}
+
+// setState will update the port state, emitting an event for each transition.
+func (p *Port) setState(newState portState, reason string) {
+	p.mx.Lock()
+	oldState := p.state
+	p.state = newState
+	p.mx.Unlock()
+
+	name := newState.Name
+	if name == "" {
+		name = oldState.Name
+	}
+	emit := func(t PortEventType) { p.cli.emit(PortEvent{Type: t, Port: p, Name: name, Reason: reason}) }
+
+	if oldState.Present && newState.Present && oldState.Name != newState.Name {
+		// moved to a different serial port, treat as separate devices
+		if oldState.Open {
+			emit(PortClosed)
+		}
+		emit(PortDisappeared)
+		oldState = portState{}
+	}
+	if !oldState.Present && newState.Present {
+		emit(PortAppeared)
+	}
+	if !oldState.Open && newState.Open {
+		emit(PortOpened)
+	}
+	if oldState.Open && !newState.Open {
+		emit(PortClosed)
+	}
+	if oldState.Present && !newState.Present {
+		emit(PortDisappeared)
+	}
+}
+
+// resetState will clear the port state after losing the SPJS connection.
+func (p *Port) resetState(reason string) {
+	p.mx.Lock()
+	name := p.state.Name
+	p.state = portState{}
+	p.mx.Unlock()
+
+	p.cli.emit(PortEvent{Type: PortReconnecting, Port: p, Name: name, Reason: reason})
+}
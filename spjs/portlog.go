@@ -0,0 +1,22 @@
+package spjs
+
+import "time"
+
+// PortLogEntry is a single message sent to, or received from, a serial port.
+type PortLogEntry struct {
+	Time time.Time
+	Sent bool
+	Data string
+}
+
+// Log returns a channel of commands sent with `SendCommand` and data received from the port. It always returns the same channel.
+//
+// Entries are dropped if the channel is not read fast enough.
+func (p *Port) Log() <-chan PortLogEntry { return p.logCh }
+
+func (p *Port) logData(sent bool, data string) {
+	select {
+	case p.logCh <- PortLogEntry{Time: time.Now(), Sent: sent, Data: data}:
+	default:
+	}
+}
@@ -0,0 +1,85 @@
+package spjs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Axes lists all supported axis names in the order controllers report them.
+const Axes = "XYZABC"
+
+// Position holds a coordinate for each supported axis. Rotary axes (A, B, C) are in degrees.
+type Position struct{ X, Y, Z, A, B, C float64 }
+
+// AxisMove is a relative distance to move along a single axis. Rotary axes move in degrees.
+type AxisMove struct {
+	Axis rune
+	MM   float64
+}
+
+// IsRotaryAxis returns true if the axis is measured in degrees rather than millimeters.
+func IsRotaryAxis(axis rune) bool { return axis == 'A' || axis == 'B' || axis == 'C' }
+
+// Axis returns the value of the named axis, or zero if the axis is unknown.
+func (p Position) Axis(axis rune) float64 {
+	if v := p.axisPtr(axis); v != nil {
+		return *v
+	}
+	return 0
+}
+
+// SetAxis sets the value of the named axis, unknown axes are ignored.
+func (p *Position) SetAxis(axis rune, val float64) {
+	if v := p.axisPtr(axis); v != nil {
+		*v = val
+	}
+}
+
+// Add returns the sum of each axis of both positions.
+func (p Position) Add(o Position) Position {
+	return Position{X: p.X + o.X, Y: p.Y + o.Y, Z: p.Z + o.Z, A: p.A + o.A, B: p.B + o.B, C: p.C + o.C}
+}
+
+// Sub returns the difference of each axis of both positions.
+func (p Position) Sub(o Position) Position {
+	return Position{X: p.X - o.X, Y: p.Y - o.Y, Z: p.Z - o.Z, A: p.A - o.A, B: p.B - o.B, C: p.C - o.C}
+}
+
+func (p *Position) axisPtr(axis rune) *float64 {
+	switch axis {
+	case 'X':
+		return &p.X
+	case 'Y':
+		return &p.Y
+	case 'Z':
+		return &p.Z
+	case 'A':
+		return &p.A
+	case 'B':
+		return &p.B
+	case 'C':
+		return &p.C
+	}
+	return nil
+}
+
+// parse will read a comma-separated list of coordinates, in `Axes` order, returning the number of axes read.
+func (p *Position) parse(s string) (int, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) > len(Axes) {
+		return 0, fmt.Errorf("too many axes (%d)", len(parts))
+	}
+
+	var pos Position
+	for i, part := range parts {
+		val, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return 0, err
+		}
+		pos.SetAxis(rune(Axes[i]), val)
+	}
+	*p = pos
+
+	return len(parts), nil
+}
@@ -0,0 +1,71 @@
+package spjs
+
+// QueueStats are the command counts SPJS reported for a port, along with the controller buffer state.
+type QueueStats struct {
+	// Queued is the number of commands currently queued in SPJS (`QCnt`).
+	Queued int
+
+	// Added, Written and Completed are the total number of commands SPJS has reported for each stage. Commands
+	// sent without tracking completion, like status polls, are not counted.
+	Added     int
+	Written   int
+	Completed int
+
+	// PlannerFree and RXFree are the free planner blocks and serial RX bytes reported by the controller, or -1 if unknown.
+	PlannerFree int
+	RXFree      int
+}
+
+// QueueStats returns the current queue and buffer counts for the port.
+func (p *Port) QueueStats() QueueStats {
+	p.statsMx.Lock()
+	stats := p.stats
+	p.statsMx.Unlock()
+
+	stats.PlannerFree, stats.RXFree = -1, -1
+	if b, ok := p.Driver().(BufferReporter); ok {
+		if planner, rx, ok := b.BufferFree(); ok {
+			stats.PlannerFree, stats.RXFree = planner, rx
+		}
+	}
+
+	return stats
+}
+
+// QueueCount returns the number of commands SPJS last reported as queued for this port.
+func (p *Port) QueueCount() int {
+	p.statsMx.Lock()
+	defer p.statsMx.Unlock()
+	return p.stats.Queued
+}
+
+// queueChanged returns a channel that receives a value after each change to the queue stats.
+func (p *Port) queueChanged() <-chan struct{} { return p.statsCh }
+
+// updateQueueStats will update the stats from an SPJS command message. Only IDs that tracked returns true for are counted.
+func (p *Port) updateQueueStats(m CommandMessage, tracked func(id string) bool) {
+	var added int
+	for _, id := range m.IDs {
+		if tracked(id) {
+			added++
+		}
+	}
+	isTracked := m.ID != "" && tracked(m.ID)
+
+	p.statsMx.Lock()
+	p.stats.Queued = m.QCnt
+	switch {
+	case m.Cmd == "Queued":
+		p.stats.Added += added
+	case m.Cmd == "Write" && isTracked:
+		p.stats.Written++
+	case m.Cmd == "Complete" && isTracked:
+		p.stats.Completed++
+	}
+	p.statsMx.Unlock()
+
+	select {
+	case p.statsCh <- struct{}{}:
+	default:
+	}
+}
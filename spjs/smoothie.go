@@ -0,0 +1,138 @@
+package spjs
+
+import (
+	"context"
+	"strings"
+)
+
+// Smoothie is a driver for Smoothieware based controllers (e.g. Smoothieboard).
+type Smoothie struct {
+	*statusPoller
+
+	firstStatus bool
+	statCh      chan SmoothieStatus
+	statExtCh   chan ControllerStatus
+}
+
+var _ Driver = &Smoothie{}
+
+func NewSmoothie() *Smoothie {
+	return &Smoothie{
+		statCh:       make(chan SmoothieStatus, 1),
+		statExtCh:    make(chan ControllerStatus),
+		statusPoller: newStatusPoller("Smoothie", "?"),
+	}
+}
+
+// SetPort will start polling for status reports on the port.
+func (s *Smoothie) SetPort(p *Port) {
+	go func() {
+		for e := range p.Events() {
+			if e.Type == PortOpened {
+				s.statusPoller.reset()
+			}
+		}
+	}()
+	go s.statusPoller.run(p.cli.ctx, p)
+}
+
+// Name will always return the string `Smoothie`.
+func (s *Smoothie) Name() string { return "Smoothie" }
+
+// BufferAlgorithm returns the string `smoothie`.
+func (s *Smoothie) BufferAlgorithm() string { return "smoothie" }
+
+// BaudRate is always set to 115200, it is ignored by the native USB port.
+func (s *Smoothie) BaudRate() int { return 115200 }
+
+func (s *Smoothie) WrapGCode(data []string) string { return strings.Join(data, "\n") + "\n" }
+
+func (s *Smoothie) FeedHold() string   { return "!" }
+func (s *Smoothie) CycleStart() string { return "~" }
+
+// Home uses `G28.2`, as `G28` moves to the stored park position on Smoothie.
+func (s *Smoothie) Home() string { return "G28.2\n" }
+
+// EStop uses `M112`, the machine stays halted until `M999` or `$X` is sent.
+func (s *Smoothie) EStop() string { return "M112\n" }
+
+// Reset will abort the current job, leaving the machine halted the same as GRBL's alarm state.
+func (s *Smoothie) Reset() string { return "\x18" }
+
+// Jog uses a relative rapid move, as jogging (`$J`) is not available in all Smoothie builds.
+//
+// The modal units and distance mode are saved with `M120` and restored with `M121` afterwards.
+func (s *Smoothie) Jog(moves ...AxisMove) string {
+	var buf strings.Builder
+	buf.WriteString("M120\nG91G21G0")
+	for _, m := range moves {
+		buf.WriteString(Millimeters.axisWord(m.Axis, m.MM))
+	}
+	buf.WriteString("\nM121\n")
+	return buf.String()
+}
+
+// JogFeed is the same as Jog at the provided feed rate, used for MPG jogs. Smoothie can't cancel a jog,
+// so it is followed by `M400` and completes once the move has finished, letting the MPG keep no more than
+// one move ahead of the machine. The feed rate is saved and restored with the modal state.
+func (s *Smoothie) JogFeed(feed float64, moves ...AxisMove) string {
+	var buf strings.Builder
+	buf.WriteString("M120\nG91G21G1")
+	for _, m := range moves {
+		buf.WriteString(Millimeters.axisWord(m.Axis, m.MM))
+	}
+	buf.WriteString(Millimeters.feedWord(feed))
+	buf.WriteString("\nM121\nM400\n")
+	return buf.String()
+}
+
+// WPos uses G21 so that offsets are set in mm, the modal units are saved and restored the same as Jog.
+func (s *Smoothie) WPos(axis rune, mm float64) string {
+	return "M120\nG21G10L20P1" + Millimeters.axisWord(axis, mm) + "\nM121\n"
+}
+
+// CurrentStatus returns the last status without waiting for the first status message.
+func (s *Smoothie) CurrentStatus() (ControllerStatus, bool) {
+	select {
+	case stat := <-s.statCh:
+		s.statCh <- stat
+		return stat, true
+	default:
+		return nil, false
+	}
+}
+
+// Status will return a channel that will get updates each time status data is updated. It always returns the same channel.
+func (s *Smoothie) Status() <-chan ControllerStatus { return s.statExtCh }
+
+// HandleData will process data coming from Smoothie. It is only intended to be used by the SPJS client code.
+func (s *Smoothie) HandleData(ctx context.Context, data string) error {
+	if !strings.HasPrefix(data, "<") {
+		return nil
+	}
+
+	var stat SmoothieStatus
+	if s.firstStatus {
+		stat = <-s.statCh
+	}
+
+	newStat := stat
+	err := newStat.Parse(data)
+	if err != nil {
+		if s.firstStatus {
+			s.statCh <- stat
+		}
+		return err
+	}
+
+	s.firstStatus = true
+	s.statCh <- newStat
+	s.statusPoller.reported(newStat.State != GRBLStateIdle && newStat.State != GRBLStateAlarm)
+
+	select {
+	case s.statExtCh <- newStat:
+	default:
+	}
+
+	return nil
+}
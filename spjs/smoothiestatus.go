@@ -0,0 +1,117 @@
+package spjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SmoothieStatus is a parsed Smoothieware status report. Positions are always reported in mm.
+type SmoothieStatus struct {
+	Status string
+	State  GRBLState
+
+	MPos, WPos Position
+
+	// Axes is the number of axes included in position reports.
+	Axes int
+
+	Feed float64
+
+	// FeedOverride is the feed override percentage, it is only included in the new status format.
+	FeedOverride float64
+}
+
+var _ ControllerStatus = SmoothieStatus{}
+
+func (stat SmoothieStatus) IsAlarm() bool             { return stat.State == GRBLStateAlarm }
+func (stat SmoothieStatus) IsReady() bool             { return stat.State == GRBLStateIdle }
+func (stat SmoothieStatus) MachinePosition() Position { return stat.MPos }
+func (stat SmoothieStatus) WorkPosition() Position    { return stat.WPos }
+func (stat SmoothieStatus) StatusText() string        { return stat.Status }
+func (stat SmoothieStatus) FeedRate() float64         { return stat.Feed }
+
+// Accessories always returns an empty status, Smoothie does not include outputs in status reports.
+func (stat SmoothieStatus) Accessories() AccessoryStatus { return AccessoryStatus{} }
+
+func (stat SmoothieStatus) AxisCount() int {
+	if stat.Axes == 0 {
+		return 3
+	}
+	return stat.Axes
+}
+
+// Parse will parse a status report in either the new (`<Idle|MPos:...|WPos:...|F:...>`)
+// or old (`<Idle,MPos:...,WPos:...>`) format.
+func (stat *SmoothieStatus) Parse(data string) error {
+	data = strings.TrimSpace(data)
+	data = strings.TrimPrefix(data, "<")
+	data = strings.TrimSuffix(data, ">")
+
+	if !strings.Contains(data, "|") {
+		return stat.parseOld(data)
+	}
+
+	parts := strings.Split(data, "|")
+	err := stat.parseState(parts[0])
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts[1:] {
+		p := strings.SplitN(part, ":", 2)
+		if len(p) != 2 {
+			continue
+		}
+
+		switch p[0] {
+		case "MPos":
+			stat.Axes, err = stat.MPos.parse(p[1])
+		case "WPos":
+			_, err = stat.WPos.parse(p[1])
+		case "F":
+			// current feed rate, and optionally the override percentage
+			f := strings.SplitN(p[1], ",", 2)
+			_, err = fmt.Sscanf(f[0], "%f", &stat.Feed)
+			if err == nil && len(f) == 2 {
+				_, err = fmt.Sscanf(f[1], "%f", &stat.FeedOverride)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("parse %s '%s': %w", p[0], p[1], err)
+		}
+	}
+
+	return nil
+}
+
+func (stat *SmoothieStatus) parseOld(data string) error {
+	mIdx := strings.Index(data, ",MPos:")
+	wIdx := strings.Index(data, ",WPos:")
+	if mIdx == -1 || wIdx == -1 || wIdx < mIdx {
+		return fmt.Errorf("unknown status format '%s'", data)
+	}
+
+	err := stat.parseState(data[:mIdx])
+	if err != nil {
+		return err
+	}
+
+	stat.Axes, err = stat.MPos.parse(data[mIdx+len(",MPos:") : wIdx])
+	if err != nil {
+		return fmt.Errorf("parse MPos: %w", err)
+	}
+	_, err = stat.WPos.parse(data[wIdx+len(",WPos:"):])
+	if err != nil {
+		return fmt.Errorf("parse WPos: %w", err)
+	}
+
+	return nil
+}
+
+func (stat *SmoothieStatus) parseState(s string) error {
+	stat.Status = s
+
+	var err error
+	stat.State, _, err = parseGRBLState(s)
+	return err
+}
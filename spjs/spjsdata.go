@@ -2,7 +2,6 @@ package spjs
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,22 +9,6 @@ import (
 	"strings"
 )
 
-type SPJSData struct {
-	Version     string
-	Commands    []string
-	Hostname    string
-	SerialPorts []SerialPort
-
-	P         string
-	D         string
-	Cmd       string
-	ID        string
-	ErrorCode string
-
-	Port string
-	QCnt int
-}
-
 func (c *Client) PortByName(name string) *Port {
 	ports := <-c.ports
 	c.ports <- ports
@@ -40,6 +23,13 @@ func (c *Client) PortByName(name string) *Port {
 	return nil
 }
 
+// SerialPorts returns the serial ports from the most recent SPJS port list.
+func (c *Client) SerialPorts() []SerialPort {
+	ports := <-c.serialPorts
+	c.serialPorts <- ports
+	return ports
+}
+
 func (c *Client) updatePorts(serialPorts []SerialPort) {
 	<-c.serialPorts
 	c.serialPorts <- serialPorts
@@ -47,13 +37,25 @@ func (c *Client) updatePorts(serialPorts []SerialPort) {
 	ports := <-c.ports
 	c.ports <- ports
 
+	for _, port := range ports {
+		var state portState
+		for _, sp := range serialPorts {
+			if !port.Matches(sp) {
+				continue
+			}
+			state = portState{Name: sp.Name, Present: true, Open: sp.IsOpen}
+			break
+		}
+		port.setState(state, "SPJS port list")
+	}
+
 	// open matching ports
 	for _, sp := range serialPorts {
 		if sp.IsOpen {
 			continue
 		}
 		for _, port := range ports {
-			if !port.match(sp) {
+			if !port.Matches(sp) {
 				continue
 			}
 			err := port.open(sp.Name)
@@ -66,91 +68,141 @@ func (c *Client) updatePorts(serialPorts []SerialPort) {
 }
 
 func (c *Client) readLoop(ctx context.Context) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	for dataStr := range c.dataCh {
+	defer c.wg.Done()
+
+	for {
+		var dataStr string
+		select {
+		case dataStr = <-c.dataCh:
+		case <-ctx.Done():
+			return
+		}
 		if strings.Contains(dataStr, "SerialPorts") {
 			log.Println("READ:", "...serial port data omitted...")
 		} else {
 			log.Println("READ:", dataStr)
 		}
-		if !strings.HasPrefix(dataStr, "{") || !strings.HasSuffix(dataStr, "}") {
-			continue
-		}
-		var data SPJSData
-		err := json.Unmarshal([]byte(dataStr), &data)
+		msg, err := decodeMessage(dataStr)
 		if err != nil {
-			log.Fatalf("ERROR: parse SPJS payload (%s): %v", dataStr, err)
+			log.Printf("ERROR: handle SPJS message (%s): %v", dataStr, err)
+			c.badMessage(dataStr, err)
 			continue
 		}
 
-		if data.SerialPorts != nil {
-			c.updatePorts(data.SerialPorts)
-			continue
+		switch m := msg.(type) {
+		case *PortListMessage:
+			c.updatePorts(m.SerialPorts)
+		case *VersionMessage:
+			log.Printf("SPJS version %s on %s", m.Version, m.Hostname)
+		case *ErrorMessage:
+			log.Println("ERROR: SPJS:", m.Error)
+		case *SerialDataMessage:
+			c.handleSerialData(ctx, *m)
+		case *CommandMessage:
+			c.handleCommand(*m)
+		case TextMessage:
+			// plain text replies are informational, they were logged above
 		}
+	}
+}
 
-		if data.P != "" && data.Cmd == "" && data.D != "" {
-			port := c.PortByName(data.P)
-			if port == nil {
-				continue
-			}
-			err := port.drv.HandleData(ctx, data.D)
-			if err != nil {
-				log.Printf(`ERROR: handle serial data "%s" (%s): %v`, data.D, port.drv.Name(), err)
-			}
-			continue
-		}
+func (c *Client) handleSerialData(ctx context.Context, m SerialDataMessage) {
+	port := c.PortByName(m.P)
+	if port == nil {
+		return
+	}
+	port.logData(false, m.D)
+	err := port.Driver().HandleData(ctx, m.D)
+	if err != nil {
+		log.Printf(`ERROR: handle serial data "%s" (%s): %v`, m.D, port.Driver().Name(), err)
+	}
+}
 
-		var baseID string
-		cmdID := commandID{Port: data.P}
-		if cmdID.Port == "" {
-			cmdID.Port = data.Port
+func (c *Client) handleCommand(m CommandMessage) {
+	portName := m.PortName()
+	switch m.Cmd {
+	case "Queued", "Write", "Complete", "WipedQueue":
+		if port := c.PortByName(portName); port != nil {
+			port.updateQueueStats(m, func(id string) bool { return c.hasCallback(portName, id) })
 		}
-		if data.Cmd == "Open" {
-			io.WriteString(c, "list")
-		}
-		idStr := strings.ReplaceAll(data.ID, "-", " ")
-		if idStr == "" {
-			continue
-		}
-		_, err = fmt.Sscanf(idStr, "%s %d", &baseID, &cmdID.ID)
-		if err != nil {
-			log.Printf(`ERROR: unknown ID format "%s"`, data.ID)
-			continue
+	}
+
+	switch m.Cmd {
+	case "Open":
+		io.WriteString(c, "list")
+		if port := c.PortByName(portName); port != nil {
+			port.setState(portState{Name: portName, Present: true, Open: true}, "opened by SPJS")
 		}
-		if baseID != c.baseID {
-			continue
+	case "Close":
+		if port := c.PortByName(portName); port != nil {
+			port.setState(portState{Name: portName, Present: true}, "closed by SPJS")
 		}
+	}
 
-		switch data.Cmd {
-		case "Write":
-			c.withOneCallback(cmdID, func(cb *commandCallback) bool {
-				cb.written()
-				return false
-			})
-		case "Complete":
-			c.withOneCallback(cmdID, func(cb *commandCallback) bool {
-				cb.finish(nil)
-
-				return false
-			})
-		case "Error":
-			c.withOneCallback(cmdID, func(cb *commandCallback) bool {
-				cb.finish(errors.New(data.ErrorCode))
-				return true
-			})
-		case "WipedQueue", "Close":
-			err := errors.New("RESET")
-			c.withCallbacks(func(m callbackMap) {
-				for id, cb := range m {
-					if id.Port != data.P {
-						continue
-					}
-					cb.finish(err)
-					delete(m, id)
+	switch m.Cmd {
+	case "WipedQueue", "Close":
+		err := errors.New("RESET")
+		c.withCallbacks(func(cbs callbackMap) {
+			for id, cb := range cbs {
+				if id.Port != portName {
+					continue
 				}
-			})
-		}
+				cb.finish(err)
+				delete(cbs, id)
+			}
+		})
+		return
 	}
+
+	if m.ID == "" {
+		return
+	}
+	baseID, cmdID, err := parseCommandID(portName, m.ID)
+	if err != nil {
+		log.Printf(`ERROR: unknown ID format "%s"`, m.ID)
+		return
+	}
+	if baseID != c.baseID {
+		return
+	}
+
+	switch m.Cmd {
+	case "Write":
+		c.withOneCallback(cmdID, func(cb *commandCallback) bool {
+			cb.written()
+			return false
+		})
+	case "Complete":
+		c.withOneCallback(cmdID, func(cb *commandCallback) bool {
+			cb.finish(nil)
+
+			return false
+		})
+	case "Error":
+		c.withOneCallback(cmdID, func(cb *commandCallback) bool {
+			cb.finish(errors.New(m.ErrorCode))
+			return true
+		})
+	}
+}
+
+// parseCommandID will parse an SPJS ID in the form `<baseID>-<id>`.
+func parseCommandID(portName, id string) (string, commandID, error) {
+	var baseID string
+	cmdID := commandID{Port: portName}
+	_, err := fmt.Sscanf(strings.ReplaceAll(id, "-", " "), "%s %d", &baseID, &cmdID.ID)
+	return baseID, cmdID, err
+}
+
+// hasCallback returns true if the SPJS ID is for a command sent by this client with a callback. Commands sent
+// with sendQuiet (e.g. status polls) have none.
+func (c *Client) hasCallback(portName, id string) bool {
+	baseID, cmdID, err := parseCommandID(portName, id)
+	if err != nil || baseID != c.baseID {
+		return false
+	}
+
+	var ok bool
+	c.withCallbacks(func(cbs callbackMap) { _, ok = cbs[cmdID] })
+	return ok
 }
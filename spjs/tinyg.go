@@ -0,0 +1,210 @@
+package spjs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// tinyGStatusFields are the values requested in status reports.
+const tinyGStatusFields = `{"sr":{"stat":t,"unit":t,"dist":t,"feed":t,"vel":t,` +
+	`"posx":t,"posy":t,"posz":t,"posa":t,"posb":t,"posc":t,` +
+	`"mpox":t,"mpoy":t,"mpoz":t,"mpoa":t,"mpob":t,"mpoc":t}}`
+
+// TinyG is a driver for TinyG and g2core controllers running in JSON mode.
+type TinyG struct {
+	port *Port
+	g2   bool
+
+	*statusPoller
+
+	statCh    chan TinyGStatus
+	statExtCh chan ControllerStatus
+}
+
+var _ Driver = &TinyG{}
+
+// NewTinyG returns a driver for TinyG (v8) controllers.
+func NewTinyG() *TinyG { return newTinyG(false) }
+
+// NewG2Core returns a driver for g2core controllers.
+func NewG2Core() *TinyG { return newTinyG(true) }
+
+func newTinyG(g2 bool) *TinyG {
+	t := &TinyG{
+		g2:        g2,
+		statCh:    make(chan TinyGStatus, 1),
+		statExtCh: make(chan ControllerStatus),
+	}
+	t.statusPoller = newStatusPoller(t.Name(), `{"sr":null}`+"\n")
+	t.statCh <- TinyGStatus{}
+	return t
+}
+
+// SetPort will set the control port. JSON mode and status reports are configured each time the port is opened.
+func (t *TinyG) SetPort(p *Port) {
+	t.port = p
+	go func() {
+		for e := range p.Events() {
+			if e.Type != PortOpened {
+				continue
+			}
+			t.statusPoller.reset()
+			t.configure(context.Background())
+		}
+	}()
+	go t.statusPoller.run(p.cli.ctx, p)
+}
+
+// configure enables JSON mode, sets which values are included in status reports, and queries which axes are enabled.
+func (t *TinyG) configure(ctx context.Context) {
+	cmds := []string{`{"ej":1}`, `{"jv":4}`, tinyGStatusFields}
+	for _, axis := range strings.ToLower(Axes) {
+		cmds = append(cmds, `{"`+string(axis)+`am":n}`)
+	}
+	for _, cmd := range cmds {
+		err := t.port.SendCommand(ctx, cmd+"\n", false)
+		if err != nil {
+			log.Printf("ERROR: configure %s (%s): %v", t.Name(), cmd, err)
+			return
+		}
+	}
+}
+
+// Name returns `g2core` or `TinyG`.
+func (t *TinyG) Name() string {
+	if t.g2 {
+		return "g2core"
+	}
+	return "TinyG"
+}
+
+// BufferAlgorithm returns `tinygg2` or `tinyg`.
+func (t *TinyG) BufferAlgorithm() string {
+	if t.g2 {
+		return "tinygg2"
+	}
+	return "tinyg"
+}
+
+// BaudRate is always set to 115200, it is ignored by native USB ports.
+func (t *TinyG) BaudRate() int { return 115200 }
+
+func (t *TinyG) WrapGCode(data []string) string { return strings.Join(data, "\n") + "\n" }
+
+func (t *TinyG) FeedHold() string   { return "!" }
+func (t *TinyG) CycleStart() string { return "~" }
+func (t *TinyG) Reset() string      { return "\x18" }
+
+// JogCancel uses a feed hold followed by a queue flush (`!%`), which stops motion and drops planned moves.
+func (t *TinyG) JogCancel() string { return "!%" }
+
+// Home uses `G28.2`, which requires each axis to be homed to be listed.
+func (t *TinyG) Home() string { return "G28.2X0Y0Z0\n" }
+
+// Jog uses a relative rapid move, as there is no jog command.
+//
+// Moves are sent in the modal units from the last status report, and the distance mode is restored afterwards.
+func (t *TinyG) Jog(moves ...AxisMove) string {
+	stat := <-t.statCh
+	t.statCh <- stat
+
+	var buf strings.Builder
+	buf.WriteString("G91G0")
+	for _, m := range moves {
+		buf.WriteString(stat.Units.axisWord(m.Axis, m.MM))
+	}
+	buf.WriteString("\n")
+	if !stat.Incremental {
+		buf.WriteString("G90\n")
+	}
+	return buf.String()
+}
+
+// WPos is sent in the modal units from the last status report, so they are left unchanged.
+func (t *TinyG) WPos(axis rune, mm float64) string {
+	stat := <-t.statCh
+	t.statCh <- stat
+	return "G10L20P1" + stat.Units.axisWord(axis, mm) + "\n"
+}
+
+// CurrentStatus returns the last status, it is always available.
+func (t *TinyG) CurrentStatus() (ControllerStatus, bool) {
+	stat := <-t.statCh
+	t.statCh <- stat
+	return stat, true
+}
+
+// Status will return a channel that will get updates each time status data is updated. It always returns the same channel.
+func (t *TinyG) Status() <-chan ControllerStatus { return t.statExtCh }
+
+type tinyGResponse struct {
+	SR map[string]float64 `json:"sr"`
+
+	// R is the response to a command, e.g. `{"sr":{...}}` or `{"xam":1}`.
+	R  map[string]json.RawMessage `json:"r"`
+	ER *struct {
+		Msg string `json:"msg"`
+	} `json:"er"`
+}
+
+// HandleData will process data coming from TinyG/g2core. It is only intended to be used by the SPJS client code.
+func (t *TinyG) HandleData(ctx context.Context, data string) error {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, "{") {
+		return nil
+	}
+
+	var resp tinyGResponse
+	err := json.Unmarshal([]byte(data), &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.ER != nil {
+		log.Printf("ERROR: %s: %s", t.Name(), resp.ER.Msg)
+	}
+
+	sr := resp.SR
+	if sr == nil && resp.R["sr"] != nil {
+		err = json.Unmarshal(resp.R["sr"], &sr)
+		if err != nil {
+			return err
+		}
+	}
+
+	modes := make(map[byte]float64)
+	for key, val := range resp.R {
+		if len(key) != 3 || !strings.HasSuffix(key, "am") {
+			continue
+		}
+		var mode float64
+		err = json.Unmarshal(val, &mode)
+		if err != nil {
+			return fmt.Errorf("parse axis mode %s: %w", key, err)
+		}
+		modes[key[0]] = mode
+	}
+	if sr == nil && len(modes) == 0 {
+		return nil
+	}
+
+	stat := <-t.statCh
+	for axis, mode := range modes {
+		stat.setAxisMode(axis, mode)
+	}
+	if sr != nil {
+		stat.update(sr)
+	}
+	t.statCh <- stat
+	t.statusPoller.reported(stat.isMoving())
+
+	select {
+	case t.statExtCh <- stat:
+	default:
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package spjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TinyGState is the machine state (`stat`) from a TinyG or g2core status report.
+type TinyGState int
+
+const (
+	TinyGStateInit TinyGState = iota
+	TinyGStateReady
+	TinyGStateAlarm
+	TinyGStateStop
+	TinyGStateEnd
+	TinyGStateRun
+	TinyGStateHold
+	TinyGStateProbe
+	TinyGStateCycle
+	TinyGStateHoming
+	TinyGStateJog
+	TinyGStateInterlock
+	TinyGStateShutdown
+	TinyGStatePanic
+)
+
+var tinyGStateNames = []string{
+	TinyGStateInit:      "Init",
+	TinyGStateReady:     "Ready",
+	TinyGStateAlarm:     "Alarm",
+	TinyGStateStop:      "Stop",
+	TinyGStateEnd:       "End",
+	TinyGStateRun:       "Run",
+	TinyGStateHold:      "Hold",
+	TinyGStateProbe:     "Probe",
+	TinyGStateCycle:     "Cycle",
+	TinyGStateHoming:    "Homing",
+	TinyGStateJog:       "Jog",
+	TinyGStateInterlock: "Interlock",
+	TinyGStateShutdown:  "Shutdown",
+	TinyGStatePanic:     "Panic",
+}
+
+func (s TinyGState) String() string {
+	if s < 0 || int(s) >= len(tinyGStateNames) {
+		return fmt.Sprintf("TinyGState(%d)", int(s))
+	}
+	return tinyGStateNames[s]
+}
+
+// TinyGStatus is the merged state from TinyG/g2core status reports. Positions are stored in mm.
+type TinyGStatus struct {
+	State TinyGState
+
+	MPos, WPos Position
+
+	// Axes is the number of axes up to the last one enabled by the controller (axis mode `am` not 0),
+	// XYZ are always included.
+	Axes int
+
+	// enabled is a bitmask of axes enabled by the controller, in the order of Axes.
+	enabled uint8
+
+	// Units are the current modal units (`unit`), work positions and rates are reported in them.
+	Units Units
+
+	// Incremental is true if the modal distance mode (`dist`) is G91.
+	Incremental bool
+
+	// Feed is the programmed feed rate and Velocity is the actual current rate.
+	Feed     float64
+	Velocity float64
+}
+
+var _ ControllerStatus = TinyGStatus{}
+
+func (stat TinyGStatus) MachinePosition() Position { return stat.MPos }
+func (stat TinyGStatus) WorkPosition() Position    { return stat.WPos }
+func (stat TinyGStatus) StatusText() string        { return stat.State.String() }
+func (stat TinyGStatus) FeedRate() float64         { return stat.Velocity }
+
+// Accessories always returns an empty status, outputs are not included in status reports.
+func (stat TinyGStatus) Accessories() AccessoryStatus { return AccessoryStatus{} }
+
+func (stat TinyGStatus) AxisCount() int {
+	if stat.Axes == 0 {
+		return 3
+	}
+	return stat.Axes
+}
+
+func (stat TinyGStatus) IsReady() bool {
+	switch stat.State {
+	case TinyGStateReady, TinyGStateStop, TinyGStateEnd:
+		return true
+	}
+	return false
+}
+func (stat TinyGStatus) IsAlarm() bool {
+	switch stat.State {
+	case TinyGStateAlarm, TinyGStateInterlock, TinyGStateShutdown, TinyGStatePanic:
+		return true
+	}
+	return false
+}
+
+// isMoving returns true if the state is one where the machine may be in motion.
+func (stat TinyGStatus) isMoving() bool {
+	switch stat.State {
+	case TinyGStateRun, TinyGStateHold, TinyGStateProbe, TinyGStateCycle, TinyGStateHoming, TinyGStateJog:
+		return true
+	}
+	return false
+}
+
+// update will apply the values from a status report (`sr`). Reports only include values that have changed.
+func (stat *TinyGStatus) update(sr map[string]float64) {
+	// units must be known before converting positions from the same report
+	if u, ok := sr["unit"]; ok {
+		stat.Units = Millimeters
+		if u == 0 {
+			stat.Units = Inches
+		}
+	}
+
+	for key, val := range sr {
+		switch {
+		case key == "stat":
+			stat.State = TinyGState(val)
+		case key == "dist":
+			stat.Incremental = val == 1
+		case key == "feed":
+			stat.Feed = stat.Units.ToMM(val)
+		case key == "vel":
+			stat.Velocity = stat.Units.ToMM(val)
+		case len(key) == 4 && strings.HasPrefix(key, "pos"):
+			stat.setAxis(&stat.WPos, key[3], val, stat.Units)
+		case len(key) == 4 && strings.HasPrefix(key, "mpo"):
+			// machine positions are always reported in mm
+			stat.setAxis(&stat.MPos, key[3], val, Millimeters)
+		}
+	}
+}
+
+func (stat *TinyGStatus) setAxis(pos *Position, axis byte, val float64, units Units) {
+	i := strings.IndexByte(strings.ToLower(Axes), axis)
+	if i == -1 {
+		return
+	}
+	r := rune(Axes[i])
+	if !IsRotaryAxis(r) {
+		val = units.ToMM(val)
+	}
+	pos.SetAxis(r, val)
+}
+
+// setAxisMode will record if an axis is enabled, from an axis mode (`am`) response. A mode of 0 is disabled.
+func (stat *TinyGStatus) setAxisMode(axis byte, mode float64) {
+	i := strings.IndexByte(strings.ToLower(Axes), axis)
+	if i == -1 {
+		return
+	}
+	if mode == 0 {
+		stat.enabled &^= 1 << i
+	} else {
+		stat.enabled |= 1 << i
+	}
+
+	stat.Axes = 3
+	for j := len(Axes) - 1; j >= 3; j-- {
+		if stat.enabled&(1<<j) != 0 {
+			stat.Axes = j + 1
+			break
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package spjs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTinyGStatusUpdate(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		g2    bool
+		lines []string
+		want  TinyGStatus
+	}{
+		{
+			name:  "TinyG",
+			lines: []string{`{"sr":{"posx":1.000,"posy":2.000,"posz":-0.500,"mpox":11.000,"mpoy":2.000,"mpoz":-0.500,"vel":0.00,"stat":3,"unit":1,"dist":0,"feed":600.00}}`},
+			want: TinyGStatus{
+				State: TinyGStateStop, Units: Millimeters, Feed: 600,
+				WPos: Position{X: 1, Y: 2, Z: -0.5},
+				MPos: Position{X: 11, Y: 2, Z: -0.5},
+			},
+		},
+		{
+			name: "TinyG inches",
+			lines: []string{
+				`{"sr":{"unit":0,"posx":1.0000,"posa":90.000,"mpox":25.400,"vel":10.00,"stat":5,"dist":1}}`,
+				`{"sr":{"posy":0.5000,"stat":1}}`,
+			},
+			want: TinyGStatus{
+				State: TinyGStateReady, Units: Inches, Incremental: true, Velocity: 254,
+				WPos: Position{X: 25.4, Y: 12.7, A: 90},
+				MPos: Position{X: 25.4},
+			},
+		},
+		{
+			name: "g2core",
+			g2:   true,
+			lines: []string{
+				`{"r":{"sr":{"stat":1,"unit":1,"posx":5.000,"mpox":5.000,"vel":0}},"f":[1,0,10]}`,
+				`{"r":{"aam":1},"f":[1,0,9]}`,
+				`{"r":{"bam":0},"f":[1,0,9]}`,
+				`{"sr":{"stat":9,"posx":6.000}}`,
+			},
+			want: TinyGStatus{
+				State: TinyGStateHoming, Units: Millimeters, Axes: 4, enabled: 1 << 3,
+				WPos: Position{X: 6},
+				MPos: Position{X: 5},
+			},
+		},
+	} {
+		drv := newTinyG(c.g2)
+		for _, line := range c.lines {
+			err := drv.HandleData(context.Background(), line)
+			if err != nil {
+				t.Errorf("%s: %s: %v", c.name, line, err)
+			}
+		}
+		stat := <-drv.statCh
+		if !reflect.DeepEqual(stat, c.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", c.name, stat, c.want)
+		}
+	}
+}
+
+func TestTinyGStatusAxisMode(t *testing.T) {
+	var stat TinyGStatus
+	for _, c := range []struct {
+		axis byte
+		mode float64
+		want int
+	}{
+		{'x', 1, 3},
+		{'b', 3, 5},
+		{'a', 1, 5},
+		{'b', 0, 4},
+		{'a', 0, 3},
+		{'q', 1, 3},
+	} {
+		stat.setAxisMode(c.axis, c.mode)
+		if stat.AxisCount() != c.want {
+			t.Errorf("%cam=%g: AxisCount = %d; want %d", c.axis, c.mode, stat.AxisCount(), c.want)
+		}
+	}
+}
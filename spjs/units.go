@@ -0,0 +1,62 @@
+package spjs
+
+import "fmt"
+
+// Units is a unit of linear measurement.
+type Units int
+
+const (
+	Millimeters Units = iota
+	Inches
+)
+
+const mmPerInch = 25.4
+
+// String returns the abbreviated unit name (`mm` or `in`).
+func (u Units) String() string {
+	if u == Inches {
+		return "in"
+	}
+	return "mm"
+}
+
+// FromMM converts a value in millimeters to these units.
+func (u Units) FromMM(mm float64) float64 {
+	if u == Inches {
+		return mm / mmPerInch
+	}
+	return mm
+}
+
+// ToMM converts a value in these units to millimeters.
+func (u Units) ToMM(val float64) float64 {
+	if u == Inches {
+		return val * mmPerInch
+	}
+	return val
+}
+
+// axisWord formats a value in mm as a G-code axis word in these units (e.g. `X0.5`). Rotary axes are left unchanged.
+func (u Units) axisWord(axis rune, mm float64) string {
+	if u == Inches && !IsRotaryAxis(axis) {
+		// inches need an extra digit for the same resolution
+		return fmt.Sprintf("%c%.5f", axis, u.FromMM(mm))
+	}
+	return fmt.Sprintf("%c%.4f", axis, mm)
+}
+
+// feedWord formats a feed rate in mm/min as a G-code feed word in these units (e.g. `F600`).
+func (u Units) feedWord(mmPerMin float64) string {
+	if u == Inches {
+		return fmt.Sprintf("F%.2f", u.FromMM(mmPerMin))
+	}
+	return fmt.Sprintf("F%.f", mmPerMin)
+}
+
+// PositionToMM converts the linear axes of a position in these units to millimeters. Rotary axes are left unchanged.
+func (u Units) PositionToMM(p Position) Position {
+	p.X = u.ToMM(p.X)
+	p.Y = u.ToMM(p.Y)
+	p.Z = u.ToMM(p.Z)
+	return p
+}
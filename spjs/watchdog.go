@@ -0,0 +1,149 @@
+package spjs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// jobWatchdogTimeout is how long the UI may go without calling Kick before a running job is held.
+	jobWatchdogTimeout = 5 * time.Second
+
+	// jobReconcileDelay is how long to wait for SPJS to report its queue count after a feed hold.
+	jobReconcileDelay = time.Second
+)
+
+// Kick tells the watchdog the UI is still responsive. Once it has been called, a running job
+// will be held if Kick is not called again within 5 seconds.
+func (c *Controller) Kick() {
+	c.mx.Lock()
+	c.lastKick = time.Now()
+	c.mx.Unlock()
+}
+
+// ConfirmJob will continue a job that was held by the watchdog, resuming motion with a cycle start.
+//
+// Lines that never reached the controller are sent again first. Unconfirmed lines, written to the controller
+// but not acknowledged, are not re-sent, the operator must decide if the job can continue.
+func (c *Controller) ConfirmJob(ctx context.Context) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.job == nil {
+		return errors.New("no loaded job")
+	}
+	if !c.job.status().Held {
+		return nil
+	}
+
+	err := c.CommandCycleStart(ctx)
+	if err != nil {
+		return err
+	}
+	c.job.resume()
+
+	return nil
+}
+
+// jobRunning returns true if there is an active job that has not finished or failed. The caller must hold c.mx.
+func (c *Controller) jobRunning() bool {
+	if c.job == nil {
+		return false
+	}
+	stat := c.job.status()
+	return stat.Active && !stat.Done && stat.Err == nil
+}
+
+// holdJob will hold the active job, if there is one. It returns true only if the job was not already held.
+func (c *Controller) holdJob(reason string) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.jobRunning() {
+		return false
+	}
+	return c.job.hold(reason)
+}
+
+// watchdog will hold a running job if the SPJS connection is lost or the UI stops responding.
+func (c *Controller) watchdog() {
+	events := c.Events()
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	var linkLost bool
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			switch e.Type {
+			case PortReconnecting:
+				c.holdJob("lost connection to SPJS")
+
+				// reconcile even if the job was already held for another reason
+				c.mx.Lock()
+				linkLost = linkLost || c.jobRunning()
+				c.mx.Unlock()
+			case PortOpened:
+				if linkLost {
+					linkLost = false
+					go c.reconcileJob(c.cli.ctx)
+				}
+			}
+		case <-t.C:
+			c.mx.Lock()
+			lastKick := c.lastKick
+			c.mx.Unlock()
+			if lastKick.IsZero() || time.Since(lastKick) < jobWatchdogTimeout {
+				continue
+			}
+			if c.holdJob("UI not responding") {
+				c.CommandFeedHold(c.cli.ctx)
+			}
+		}
+	}
+}
+
+// reconcileJob will stop motion after reconnecting, and describe what may have been lost in the hold reason.
+//
+// Unconfirmed lines were written to the controller, so they may or may not have run. Requeued lines were
+// not, but if SPJS still reports queued commands they may have been queued there, and sending them again
+// could repeat them.
+func (c *Controller) reconcileJob(ctx context.Context) {
+	// the machine may still be running whatever SPJS had queued
+	err := c.CommandFeedHold(ctx)
+	if err != nil {
+		c.holdJob("feed hold failed after reconnect: " + err.Error())
+	}
+
+	t := time.NewTimer(jobReconcileDelay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		return
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.job == nil {
+		return
+	}
+	queued := c.QueueCount()
+	c.job.updateStatus(func(s *JobStatus) {
+		s.Queued = queued
+		if !s.Held {
+			return
+		}
+		if s.Unconfirmed > 0 {
+			s.HoldReason = fmt.Sprintf("%s; %d lines were written to the controller but not confirmed, and may not have run", s.HoldReason, s.Unconfirmed)
+		}
+		if s.Requeued > 0 && queued > 0 {
+			s.HoldReason = fmt.Sprintf("%s; SPJS still has %d commands queued, the %d lines to be sent again may repeat them", s.HoldReason, queued, s.Requeued)
+		}
+	})
+}